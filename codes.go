@@ -0,0 +1,52 @@
+package jsonschema
+
+// Codes of ValidationError, one per check. They are stable and meant for
+// programs, the messages are meant for people.
+const (
+	CodeInvalidJSON   = "invalid_json"
+	CodeInvalidValue  = "invalid_value"
+	CodeInvalidNumber = "invalid_number"
+	CodeFalseSchema   = "false_schema"
+
+	// required struct fields and required object properties
+	CodeRequired = "required"
+	CodeNotNull  = "not_null"
+	CodeNotZero  = "not_zero"
+
+	CodeType   = "type"
+	CodeFormat = "format"
+	CodeConst  = "const"
+	CodeEnum   = "enum"
+
+	CodeAnyOf         = "any_of"
+	CodeOneOf         = "one_of"
+	CodeOneOfMultiple = "one_of_multiple"
+	CodeNot           = "not"
+	CodeThen          = "then"
+	CodeElse          = "else"
+
+	CodeMinimum          = "minimum"
+	CodeMaximum          = "maximum"
+	CodeExclusiveMinimum = "exclusive_minimum"
+	CodeExclusiveMaximum = "exclusive_maximum"
+	CodeMultipleOf       = "multiple_of"
+
+	CodeMinLength = "min_length"
+	CodeMaxLength = "max_length"
+	CodePattern   = "pattern"
+
+	CodeMinItems        = "min_items"
+	CodeMaxItems        = "max_items"
+	CodeUniqueItems     = "unique_items"
+	CodeAdditionalItems = "additional_items"
+	CodeContains        = "contains"
+	CodeContainsMatch   = "contains_match"
+	CodeMinContains     = "min_contains"
+	CodeMaxContains     = "max_contains"
+
+	CodeMinProperties        = "min_properties"
+	CodeMaxProperties        = "max_properties"
+	CodeAdditionalProperties = "additional_properties"
+	CodePropertyPattern      = "property_pattern"
+	CodeDependentRequired    = "dependent_required"
+)
@@ -0,0 +1,142 @@
+package jsonschema
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// Draft -
+type Draft int
+
+// Supported drafts. The zero value accepts the keywords of every draft, with
+// both the draft 4 and the draft 6 meaning of exclusiveMinimum and
+// exclusiveMaximum.
+const (
+	Draft04 Draft = iota + 1
+	Draft06
+	Draft07
+	Draft201909
+	Draft202012
+)
+
+var draftNames = map[Draft]string{
+	Draft04:     "draft-04",
+	Draft06:     "draft-06",
+	Draft07:     "draft-07",
+	Draft201909: "draft 2019-09",
+	Draft202012: "draft 2020-12",
+}
+
+var draftURIs = map[Draft]string{
+	Draft04:     "http://json-schema.org/draft-04/schema#",
+	Draft06:     "http://json-schema.org/draft-06/schema#",
+	Draft07:     "http://json-schema.org/draft-07/schema#",
+	Draft201909: "https://json-schema.org/draft/2019-09/schema",
+	Draft202012: "https://json-schema.org/draft/2020-12/schema",
+}
+
+// String -
+func (d Draft) String() string {
+	if name, ok := draftNames[d]; ok {
+		return name
+	}
+	return "any draft"
+}
+
+// URI returns the $schema URI of the draft, or "" for the zero value.
+func (d Draft) URI() string {
+	return draftURIs[d]
+}
+
+// draftRange is the first and last draft a keyword is defined in, zero
+// meaning unbounded.
+type draftRange struct {
+	since Draft
+	until Draft
+}
+
+var keywordDrafts = map[string]draftRange{
+	"contains":          {since: Draft06},
+	"const":             {since: Draft06},
+	"prefixItems":       {since: Draft202012},
+	"additionalItems":   {until: Draft201909},
+	"propertyNames":     {since: Draft06},
+	"dependentRequired": {since: Draft201909},
+	"dependentSchemas":  {since: Draft201909},
+	"dependencies":      {until: Draft07},
+	"minContains":       {since: Draft201909},
+	"maxContains":       {since: Draft201909},
+	"if":                {since: Draft07},
+	"then":              {since: Draft07},
+	"else":              {since: Draft07},
+}
+
+// anyDraftTagKeywords are the tag keywords which earlier drafts write in
+// another form: prefixItems as an items array, dependentRequired as
+// dependencies.
+var anyDraftTagKeywords = map[string]bool{
+	"prefixItems":       true,
+	"dependentRequired": true,
+}
+
+func (d Draft) supports(keyword string) bool {
+	if d == 0 {
+		return true
+	}
+	r, ok := keywordDrafts[keyword]
+	if !ok {
+		return true
+	}
+	return (r.since == 0 || d >= r.since) && (r.until == 0 || d <= r.until)
+}
+
+func (t *tag) checkDraft(d Draft) *TagError {
+	if d == 0 {
+		return nil
+	}
+	for _, kw := range t.keywords {
+		if !d.supports(kw.name) && !anyDraftTagKeywords[kw.name] {
+			return &TagError{
+				Keyword: kw.name,
+				Offset:  kw.offset,
+				Reason:  fmt.Sprintf("keyword is not supported in %s", d),
+			}
+		}
+	}
+	for _, sub := range t.children() {
+		if err := sub.checkDraft(d); err != nil {
+			return err
+		}
+	}
+	exclusive := func(keyword string, d4 *bool, d6 *big.Rat) *TagError {
+		if d == Draft04 && d6 != nil {
+			return &TagError{Keyword: keyword, Offset: t.offset(keyword), Reason: "must be a boolean in draft-04"}
+		}
+		if d != Draft04 && d4 != nil {
+			return &TagError{Keyword: keyword, Offset: t.offset(keyword), Reason: fmt.Sprintf("must be a number in %s", d)}
+		}
+		return nil
+	}
+	if err := exclusive("exclusiveMinimum", t.exclusiveMinimumD4, t.exclusiveMinimumD6); err != nil {
+		return err
+	}
+	return exclusive("exclusiveMaximum", t.exclusiveMaximumD4, t.exclusiveMaximumD6)
+}
+
+// definitions returns the keyword holding reusable schemas.
+func (d Draft) definitions() string {
+	if d >= Draft201909 {
+		return "$defs"
+	}
+	return "definitions"
+}
+
+// Option -
+type Option func(*Validator)
+
+// WithDraft -
+func WithDraft(d Draft) Option {
+	return func(v *Validator) {
+		v.draft = d
+	}
+}
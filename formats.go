@@ -149,21 +149,6 @@ func jsonPointer(value *reflect.Value, field *reflect.StructField) error {
 	if value.Kind() != reflect.String {
 		return errors.New("format/jsonPointer: invalid value kind")
 	}
-	data := value.String()
-	for _, item := range strings.Split(data, "/") {
-		for i := 0; i < len(item); i++ {
-			if item[i] == '~' {
-				if i == len(item)-1 {
-					return errors.New("")
-				}
-				switch item[i+1] {
-				case '~', '0', '1':
-					// valid
-				default:
-					return errors.New("")
-				}
-			}
-		}
-	}
-	return nil
+	_, err := parsePointer(value.String())
+	return err
 }
@@ -0,0 +1,509 @@
+package jsonschema
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	anyType           = reflect.TypeOf((*interface{})(nil)).Elem()
+	stringType        = reflect.TypeOf("")
+)
+
+// GenerateSchema returns a JSON Schema document describing the JSON encoding
+// of data, built from the same jsonschema tags Validate uses. Named struct
+// types are emitted once under definitions and referenced with $ref.
+// Properties are named after their json tag, and every property that
+// encoding/json always emits (no omitempty) is listed as required.
+func (v *Validator) GenerateSchema(data interface{}) ([]byte, error) {
+	rt := reflect.TypeOf(data)
+	if rt == nil {
+		return nil, errors.New("jsonschema: cannot generate a schema for nil")
+	}
+	g := &generator{
+		v:           v,
+		names:       map[reflect.Type]string{},
+		definitions: map[string]interface{}{},
+	}
+	schema, err := g.valueSchema(rt)
+	if err != nil {
+		return nil, err
+	}
+	if len(g.definitions) > 0 {
+		schema[v.draft.definitions()] = g.definitions
+	}
+	if uri := v.draft.URI(); uri != "" {
+		schema["$schema"] = uri
+	}
+	return json.Marshal(schema)
+}
+
+type generator struct {
+	v           *Validator
+	names       map[reflect.Type]string
+	definitions map[string]interface{}
+}
+
+// typeSchema returns the schema of the JSON encoding of rt. Nil pointers,
+// slices and maps are encoded as null.
+func (g *generator) typeSchema(rt reflect.Type) (map[string]interface{}, error) {
+	schema, err := g.valueSchema(rt)
+	if err != nil {
+		return nil, err
+	}
+	switch rt.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		return nullSchema(schema), nil
+	}
+	return schema, nil
+}
+
+// nullSchema adds null to the types of schema. A $ref ignores the keywords
+// next to it before draft 2019-09, so it goes into an anyOf.
+func nullSchema(schema map[string]interface{}) map[string]interface{} {
+	switch t := schema["type"].(type) {
+	case string:
+		schema["type"] = []string{t, "null"}
+	case nil:
+		if ref, ok := schema["$ref"]; ok && len(schema) == 1 {
+			return map[string]interface{}{
+				"anyOf": []interface{}{
+					map[string]interface{}{"type": "null"},
+					map[string]interface{}{"$ref": ref},
+				},
+			}
+		}
+	}
+	return schema
+}
+
+// notNullSchema undoes nullSchema.
+func notNullSchema(schema map[string]interface{}) map[string]interface{} {
+	switch t := schema["type"].(type) {
+	case []string:
+		schema["type"] = t[0]
+	case nil:
+		if anyOf, ok := schema["anyOf"].([]interface{}); ok && len(schema) == 1 && len(anyOf) == 2 {
+			return anyOf[1].(map[string]interface{})
+		}
+	}
+	return schema
+}
+
+func (g *generator) valueSchema(rt reflect.Type) (map[string]interface{}, error) {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	switch {
+	case rt == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}, nil
+	case rt == jsonNumberType:
+		return map[string]interface{}{"type": "number"}, nil
+	case rt == bigIntType:
+		return map[string]interface{}{"type": "integer"}, nil
+	case rt.Implements(jsonMarshalerType), reflect.PtrTo(rt).Implements(jsonMarshalerType):
+		return map[string]interface{}{}, nil
+	case rt.Implements(textMarshalerType), reflect.PtrTo(rt).Implements(textMarshalerType):
+		return map[string]interface{}{"type": "string"}, nil
+	}
+
+	switch rt.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}, nil
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, nil
+	case reflect.Slice, reflect.Array:
+		if rt.Kind() == reflect.Slice && rt.Elem().Kind() == reflect.Uint8 {
+			// encoding/json encodes []byte as a base64 string
+			return map[string]interface{}{"type": "string"}, nil
+		}
+		items, err := g.typeSchema(rt.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": items}, nil
+	case reflect.Map:
+		values, err := g.typeSchema(rt.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		if rt.Name() == "" {
+			return g.structSchema(rt)
+		}
+		name, ok := g.names[rt]
+		if !ok {
+			name = g.definitionName(rt)
+			g.names[rt] = name
+			g.definitions[name] = nil // reserve the name for recursive types
+			schema, err := g.structSchema(rt)
+			if err != nil {
+				return nil, err
+			}
+			g.definitions[name] = schema
+		}
+		return map[string]interface{}{"$ref": "#/" + g.v.draft.definitions() + "/" + escapePointer(name)}, nil
+	}
+	return map[string]interface{}{}, nil
+}
+
+func (g *generator) definitionName(rt reflect.Type) string {
+	name := rt.Name()
+	if _, ok := g.definitions[name]; ok {
+		name = strings.Replace(rt.PkgPath(), "/", ".", -1) + "." + name
+	}
+	return name
+}
+
+func (g *generator) structSchema(rt reflect.Type) (map[string]interface{}, error) {
+	properties := map[string]interface{}{}
+	required := []string{}
+	if err := g.addFields(rt, properties, &required); err != nil {
+		return nil, err
+	}
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.Name != "_" {
+			continue
+		}
+		if tagValue := field.Tag.Get(tagName); tagValue != "" && tagValue != "-" {
+			tag, err := g.v.parseTag(tagValue)
+			if err != nil {
+				err.Type, err.Field = rt.String(), field.Name
+				return nil, err
+			}
+			g.applyTag(schema, tag, rt)
+		}
+	}
+	return schema, nil
+}
+
+func (g *generator) addFields(rt reflect.Type, properties map[string]interface{}, required *[]string) error {
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name, omitempty, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if field.Anonymous && ft.Kind() == reflect.Struct && name == "" {
+			if field.PkgPath != "" {
+				// Validate does not walk unexported embedded structs, so
+				// their fields are left undescribed
+				continue
+			}
+			// encoding/json inlines the fields of embedded structs
+			if err := g.addFields(ft, properties, required); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		tagValue, ok := lookupTag(field)
+		if !ok {
+			continue
+		}
+		schema, err := g.typeSchema(field.Type)
+		if err != nil {
+			return err
+		}
+		if omitempty {
+			// nil values are omitted, not encoded as null
+			schema = notNullSchema(schema)
+		}
+		isRequired := !omitempty
+		if tagValue != "" {
+			tag, err := g.v.parseTag(tagValue)
+			if err != nil {
+				err.Type, err.Field = rt.String(), field.Name
+				return err
+			}
+			if tag.fieldRequired && !tag.nullable && g.v.requiredMode != RequiredNullable {
+				// Validate rejects nil required fields
+				schema = notNullSchema(schema)
+			}
+			g.applyTag(schema, tag, field.Type)
+			isRequired = isRequired || tag.fieldRequired
+		}
+		properties[name] = schema
+		if isRequired {
+			*required = append(*required, name)
+		}
+	}
+	return nil
+}
+
+// jsonName returns the property name from the json tag, which is empty when
+// the tag does not set one, and false if encoding/json skips the field.
+func jsonName(field reflect.StructField) (string, bool, bool) {
+	if field.PkgPath != "" && !field.Anonymous {
+		return "", false, false
+	}
+	value := field.Tag.Get("json")
+	if value == "-" {
+		return "", false, false
+	}
+	parts := strings.Split(value, ",")
+	omitempty := false
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return parts[0], omitempty, true
+}
+
+// applyTag adds the keywords of t to schema. Keywords that do not apply to
+// a slice or array are applied to its items, as Validate does.
+func (g *generator) applyTag(schema map[string]interface{}, t *tag, rt reflect.Type) {
+	if ref, ok := schema["$ref"]; ok && g.v.draft != 0 && g.v.draft < Draft201909 {
+		// a $ref ignores the keywords next to it before draft 2019-09
+		keywords := map[string]interface{}{}
+		g.applyTag(keywords, t, rt)
+		if len(keywords) > 0 {
+			delete(schema, "$ref")
+			schema["allOf"] = []interface{}{map[string]interface{}{"$ref": ref}, keywords}
+		}
+		return
+	}
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	var rest []tagKeyword
+	for _, kw := range t.keywords {
+		if kw.name != "format" && typeKinds(rt)&tagKeywords[kw.name] == 0 {
+			rest = append(rest, kw)
+			continue
+		}
+		g.applyKeyword(schema, t, kw.name, rt)
+	}
+	if len(rest) == 0 || (rt.Kind() != reflect.Slice && rt.Kind() != reflect.Array) {
+		return
+	}
+	items, ok := schema["items"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	sub := *t
+	sub.keywords = rest
+	g.applyTag(items, &sub, rt.Elem())
+}
+
+func (g *generator) applyKeyword(schema map[string]interface{}, t *tag, keyword string, rt reflect.Type) {
+	switch keyword {
+	case "minimum":
+		schema[keyword] = jsonNumber(t.minimum)
+	case "maximum":
+		schema[keyword] = jsonNumber(t.maximum)
+	case "exclusiveMinimum":
+		if t.exclusiveMinimumD4 != nil {
+			schema[keyword] = *t.exclusiveMinimumD4
+		} else {
+			schema[keyword] = jsonNumber(t.exclusiveMinimumD6)
+		}
+	case "exclusiveMaximum":
+		if t.exclusiveMaximumD4 != nil {
+			schema[keyword] = *t.exclusiveMaximumD4
+		} else {
+			schema[keyword] = jsonNumber(t.exclusiveMaximumD6)
+		}
+	case "multipleOf":
+		schema[keyword] = jsonNumber(t.multipleOf)
+	case "minLength":
+		schema[keyword] = *t.minLength
+	case "maxLength":
+		schema[keyword] = *t.maxLength
+	case "pattern":
+		schema[keyword] = t.pattern.String()
+	case "type":
+		if len(t.types) == 1 {
+			schema[keyword] = t.types[0]
+		} else {
+			schema[keyword] = t.types
+		}
+	case "format":
+		schema[keyword] = *t.format
+	case "minItems":
+		schema[keyword] = *t.minItems
+	case "maxItems":
+		schema[keyword] = *t.maxItems
+	case "uniqueItems":
+		schema[keyword] = *t.uniqueItems
+	case "prefixItems":
+		list := make([]interface{}, len(t.prefixItems))
+		for i, sub := range t.prefixItems {
+			list[i] = g.tagSchema(sub, elemType(rt))
+		}
+		if g.v.draft == 0 || g.v.draft >= Draft202012 {
+			schema[keyword] = list
+			break
+		}
+		// an items array before draft 2020-12
+		if items, ok := schema["items"].(map[string]interface{}); ok && len(items) > 0 && t.items == nil {
+			schema["additionalItems"] = items
+		}
+		schema["items"] = list
+	case "items":
+		if len(t.prefixItems) > 0 && g.v.draft != 0 && g.v.draft < Draft202012 {
+			keyword = "additionalItems"
+		}
+		if items, ok := schema[keyword].(map[string]interface{}); ok && !t.items.falseSchema {
+			g.applyTag(items, t.items, elemType(rt))
+		} else {
+			schema[keyword] = g.tagSchema(t.items, elemType(rt))
+		}
+	case "keys", "propertyNames":
+		schema["propertyNames"] = g.tagSchema(t.keys, stringType)
+	case "values":
+		if values, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+			g.applyTag(values, t.values, rt.Elem())
+		} else {
+			schema["additionalProperties"] = g.tagSchema(t.values, rt.Elem())
+		}
+	case "contains":
+		schema[keyword] = g.tagSchema(t.contains, elemType(rt))
+	case "minContains":
+		schema[keyword] = *t.minContains
+	case "maxContains":
+		schema[keyword] = *t.maxContains
+	case "minProperties":
+		schema[keyword] = *t.minProperties
+	case "maxProperties":
+		schema[keyword] = *t.maxProperties
+	case "patternProperties":
+		if len(t.patterns) > 0 {
+			patterns := map[string]interface{}{}
+			for _, p := range t.patterns {
+				patterns[p.re.String()] = g.tagSchema(p.tag, rt.Elem())
+			}
+			schema[keyword] = patterns
+			break
+		}
+		// every key has to match, the values keep their schema
+		values := schema["additionalProperties"]
+		if values == nil {
+			values = map[string]interface{}{}
+		}
+		schema[keyword] = map[string]interface{}{t.patternProperties.String(): values}
+		schema["additionalProperties"] = false
+	case "additionalProperties":
+		if values, ok := schema[keyword].(map[string]interface{}); ok && !t.additionalProperties.falseSchema {
+			g.applyTag(values, t.additionalProperties, rt.Elem())
+		} else {
+			schema[keyword] = g.tagSchema(t.additionalProperties, rt.Elem())
+		}
+	case "dependentRequired":
+		dependencies := map[string]interface{}{}
+		for _, d := range t.dependentRequired {
+			dependencies[d.name] = d.required
+		}
+		if g.v.draft != 0 && g.v.draft < Draft201909 {
+			keyword = "dependencies"
+		}
+		schema[keyword] = dependencies
+	case "required":
+		if len(t.required) > 0 {
+			schema[keyword] = t.required
+		}
+	case "allOf", "anyOf", "oneOf":
+		var subs []*tag
+		switch keyword {
+		case "allOf":
+			subs = t.allOf
+		case "anyOf":
+			subs = t.anyOf
+		case "oneOf":
+			subs = t.oneOf
+		}
+		list := make([]interface{}, len(subs))
+		for i, sub := range subs {
+			list[i] = g.tagSchema(sub, rt)
+		}
+		schema[keyword] = list
+	case "not":
+		schema[keyword] = g.tagSchema(t.not, rt)
+	case "if":
+		schema[keyword] = g.tagSchema(t.ifTag, rt)
+	case "then":
+		schema[keyword] = g.tagSchema(t.thenTag, rt)
+	case "else":
+		schema[keyword] = g.tagSchema(t.elseTag, rt)
+	case "properties":
+		properties, ok := schema[keyword].(map[string]interface{})
+		if !ok {
+			properties = map[string]interface{}{}
+			schema[keyword] = properties
+		}
+		for _, p := range t.properties {
+			sub, ok := properties[p.name].(map[string]interface{})
+			if !ok {
+				sub = map[string]interface{}{}
+				properties[p.name] = sub
+			}
+			ft, ok := propertyType(rt, p.name)
+			if !ok {
+				ft = anyType
+			}
+			g.applyTag(sub, p.tag, ft)
+		}
+	case "enum":
+		enum := make([]interface{}, len(t.enum))
+		for i, e := range t.enum {
+			enum[i] = enumJSON(e, rt)
+		}
+		schema[keyword] = enum
+	case "const":
+		schema[keyword] = enumJSON(*t.constValue, rt)
+	}
+}
+
+func (g *generator) tagSchema(t *tag, rt reflect.Type) interface{} {
+	if t.falseSchema {
+		return false
+	}
+	schema := map[string]interface{}{}
+	g.applyTag(schema, t, rt)
+	return schema
+}
+
+// enumJSON returns an enum or const value for a field of type rt. Bare tag
+// values are strings for string fields.
+func enumJSON(e enumValue, rt reflect.Type) interface{} {
+	if e.bare && staticType(rt) == "string" {
+		return e.raw
+	}
+	return e.value
+}
+
+func jsonNumber(num *big.Rat) json.Number {
+	return json.Number(formatNumber(num))
+}
@@ -0,0 +1,250 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"testing"
+	"time"
+)
+
+type GenerateAddress struct {
+	Street string `json:"street" jsonschema:"minLength:1,maxLength:64"`
+	Zip    string `json:"zip,omitempty" jsonschema:"pattern:^[0-9]+$"`
+}
+
+type GenerateUser struct {
+	Name     string                 `json:"name" jsonschema:"maxLength:20"`
+	Age      int                    `json:"age" jsonschema:"minimum:0,exclusiveMaximum:150"`
+	Score    float64                `json:"score,omitempty" jsonschema:"multipleOf:0.5"`
+	Role     string                 `json:"role" jsonschema:"enum:[admin,user]"`
+	Level    int                    `json:"level" jsonschema:"enum:[1,2,3]"`
+	Email    string                 `json:"email" jsonschema:"format:email"`
+	Tags     []string               `json:"tags" jsonschema:"minItems:1,uniqueItems:true,maxLength:8"`
+	Address  *GenerateAddress       `json:"address,omitempty"`
+	Previous []GenerateAddress      `json:"previous,omitempty"`
+	Attrs    map[string]int         `json:"attrs,omitempty" jsonschema:"maxProperties:3,required:[id]"`
+	Created  time.Time              `json:"created"`
+	Extra    map[string]interface{} `json:"-"`
+	secret   string
+}
+
+func TestValidator_GenerateSchema(t *testing.T) {
+	validator := NewValidator()
+
+	schema, err := validator.GenerateSchema(&GenerateUser{})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$ref": "#/definitions/GenerateUser",
+		"definitions": {
+			"GenerateAddress": {
+				"type": "object",
+				"properties": {
+					"street": {"type": "string", "minLength": 1, "maxLength": 64},
+					"zip": {"type": "string", "pattern": "^[0-9]+$"}
+				},
+				"required": ["street"]
+			},
+			"GenerateUser": {
+				"type": "object",
+				"properties": {
+					"name": {"type": "string", "maxLength": 20},
+					"age": {"type": "integer", "minimum": 0, "exclusiveMaximum": 150},
+					"score": {"type": "number", "multipleOf": 0.5},
+					"role": {"type": "string", "enum": ["admin", "user"]},
+					"level": {"type": "integer", "enum": [1, 2, 3]},
+					"email": {"type": "string", "format": "email"},
+					"tags": {
+						"type": ["array", "null"],
+						"items": {"type": "string", "maxLength": 8},
+						"minItems": 1,
+						"uniqueItems": true
+					},
+					"address": {"$ref": "#/definitions/GenerateAddress"},
+					"previous": {"type": "array", "items": {"$ref": "#/definitions/GenerateAddress"}},
+					"attrs": {
+						"type": "object",
+						"additionalProperties": {"type": "integer"},
+						"maxProperties": 3,
+						"required": ["id"]
+					},
+					"created": {"type": "string", "format": "date-time"}
+				},
+				"required": ["name", "age", "role", "level", "email", "tags", "created"]
+			}
+		}
+	}`, string(schema))
+
+	type Invalid struct {
+		Num int `jsonschema:"minimun:1"`
+	}
+	_, err = validator.GenerateSchema(Invalid{})
+	assert.Error(t, err)
+}
+
+func TestValidator_GenerateSchema_Draft(t *testing.T) {
+	type Child struct {
+		Num int `json:"num" jsonschema:"exclusiveMinimum:0"`
+	}
+	type Parent struct {
+		Child Child `json:"child"`
+	}
+
+	schema, err := NewValidator(WithDraft(Draft202012)).GenerateSchema(Parent{})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$ref": "#/$defs/Parent",
+		"$defs": {
+			"Child": {
+				"type": "object",
+				"properties": {"num": {"type": "integer", "exclusiveMinimum": 0}},
+				"required": ["num"]
+			},
+			"Parent": {
+				"type": "object",
+				"properties": {"child": {"$ref": "#/$defs/Child"}},
+				"required": ["child"]
+			}
+		}
+	}`, string(schema))
+
+	schema, err = NewValidator(WithDraft(Draft07)).GenerateSchema(Child{})
+	assert.NoError(t, err)
+	assert.Contains(t, string(schema), `"$schema":"http://json-schema.org/draft-07/schema#"`)
+	assert.Contains(t, string(schema), `"#/definitions/Child"`)
+
+	// keywords next to a $ref go into an allOf before draft 2019-09
+	type Sample struct {
+		Child Child   `json:"child" jsonschema:"required:[num2]"`
+		Items []Child `json:"items" jsonschema:"items(required:[num2])"`
+	}
+	validator := NewValidator(WithDraft(Draft07))
+	doc, err := validator.GenerateSchema(Sample{})
+	assert.NoError(t, err)
+	assert.Contains(t, string(doc), `"child":{"allOf":[{"$ref":"#/definitions/Child"},{"required":["num2"]}]}`)
+	assert.Contains(t, string(doc), `"items":{"allOf":[{"$ref":"#/definitions/Child"},{"required":["num2"]}]}`)
+	compiled, err := validator.Compile(doc)
+	assert.NoError(t, err)
+	data, err := json.Marshal(Sample{})
+	assert.NoError(t, err)
+	assert.Error(t, validator.Validate(Sample{}))
+	assert.Error(t, compiled.Validate(data))
+
+	doc, err = NewValidator(WithDraft(Draft201909)).GenerateSchema(Sample{})
+	assert.NoError(t, err)
+	assert.Contains(t, string(doc), `"child":{"$ref":"#/$defs/Child","required":["num2"]}`)
+}
+
+func TestValidator_GenerateSchema_Embedded(t *testing.T) {
+	type Base struct {
+		ID string `json:"id" jsonschema:"minLength:1"`
+	}
+	type hidden struct {
+		Code string `json:"code" jsonschema:"maxLength:1"`
+	}
+	type Sample struct {
+		Base
+		hidden
+		Name string `json:"name"`
+	}
+
+	// Validate does not walk the unexported embedded struct
+	validator := NewValidator()
+	sample := Sample{Base: Base{ID: "a"}, hidden: hidden{Code: "abc"}}
+	assert.NoError(t, validator.Validate(sample))
+
+	doc, err := validator.GenerateSchema(sample)
+	assert.NoError(t, err)
+	assert.Contains(t, string(doc), `"properties":{"id":{"minLength":1,"type":"string"},"name":{"type":"string"}}`)
+	schema, err := validator.Compile(doc)
+	assert.NoError(t, err)
+	data, err := json.Marshal(sample)
+	assert.NoError(t, err)
+	assert.NoError(t, schema.Validate(data))
+}
+
+func TestValidator_GenerateSchema_Combinators(t *testing.T) {
+	type Sample struct {
+		Host string `json:"host" jsonschema:"anyOf((format:ipv4),(format:hostname)),not(enum:[localhost])"`
+	}
+
+	schema, err := NewValidator().GenerateSchema(Sample{})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$ref": "#/definitions/Sample",
+		"definitions": {
+			"Sample": {
+				"type": "object",
+				"properties": {
+					"host": {
+						"type": "string",
+						"anyOf": [{"format": "ipv4"}, {"format": "hostname"}],
+						"not": {"enum": ["localhost"]}
+					}
+				},
+				"required": ["host"]
+			}
+		}
+	}`, string(schema))
+}
+
+func TestValidator_GenerateSchema_Tuple(t *testing.T) {
+	type Sample struct {
+		Record []interface{} `json:"record" jsonschema:"prefixItems((maxLength:3),(minimum:0)),items:false"`
+	}
+
+	schema, err := NewValidator(WithDraft(Draft202012)).GenerateSchema(Sample{})
+	assert.NoError(t, err)
+	assert.Contains(t, string(schema), `"record":{"items":false,"prefixItems":[{"maxLength":3},{"minimum":0}],"type":["array","null"]}`)
+
+	schema, err = NewValidator(WithDraft(Draft07)).GenerateSchema(Sample{})
+	assert.NoError(t, err)
+	assert.Contains(t, string(schema), `"record":{"additionalItems":false,"items":[{"maxLength":3},{"minimum":0}],"type":["array","null"]}`)
+}
+
+func TestValidator_GenerateSchema_Number(t *testing.T) {
+	type Sample struct {
+		Amount  json.Number `json:"amount" jsonschema:"multipleOf:0.01"`
+		Balance *big.Int    `json:"balance" jsonschema:"minimum:0"`
+	}
+
+	schema, err := NewValidator().GenerateSchema(Sample{})
+	assert.NoError(t, err)
+	assert.Contains(t, string(schema), `"amount":{"multipleOf":0.01,"type":"number"}`)
+	assert.Contains(t, string(schema), `"balance":{"minimum":0,"type":["integer","null"]}`)
+}
+
+func TestValidator_GenerateSchema_Null(t *testing.T) {
+	type Sample struct {
+		Name    *string           `json:"name" jsonschema:"minLength:1"`
+		Tags    []string          `json:"tags"`
+		Attrs   map[string]int    `json:"attrs"`
+		Data    []byte            `json:"data"`
+		Address *GenerateAddress  `json:"address"`
+		Items   []*string         `json:"items"`
+		ID      *string           `json:"id" jsonschema:"required"`
+		Extra   map[string]string `json:"extra,omitempty"`
+	}
+	validator := NewValidator()
+
+	doc, err := validator.GenerateSchema(Sample{})
+	assert.NoError(t, err)
+	assert.Contains(t, string(doc), `"address":{"anyOf":[{"type":"null"},{"$ref":"#/definitions/GenerateAddress"}]}`)
+	assert.Contains(t, string(doc), `"id":{"type":"string"}`)
+	assert.Contains(t, string(doc), `"extra":{"additionalProperties":{"type":"string"},"type":"object"}`)
+	schema, err := validator.Compile(doc)
+	assert.NoError(t, err)
+
+	id := "a"
+	data, err := json.Marshal(Sample{ID: &id, Items: []*string{nil, &id}})
+	assert.NoError(t, err)
+	assert.NoError(t, schema.Validate(data))
+	assert.NoError(t, validator.Validate(Sample{ID: &id, Items: []*string{nil, &id}}))
+
+	// a required field is not nullable, as in Validate
+	data, err = json.Marshal(Sample{})
+	assert.NoError(t, err)
+	assert.Error(t, schema.Validate(data))
+	assert.Error(t, validator.Validate(Sample{}))
+}
@@ -0,0 +1,58 @@
+package jsonschema
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"strings"
+)
+
+// Loader loads the schema documents referenced by $ref. URIs are absolute
+// when the referencing document has an absolute $id, relative otherwise.
+type Loader interface {
+	Load(uri string) (io.ReadCloser, error)
+}
+
+// FSLoader loads schema documents from a file system. The path of the URI,
+// without its leading slash, is used as the file name, so both "user.json"
+// and "https://example.com/user.json" load the file user.json.
+func FSLoader(fsys fs.FS) Loader {
+	return &fsLoader{fsys: fsys}
+}
+
+type fsLoader struct {
+	fsys fs.FS
+}
+
+func (l *fsLoader) Load(uri string) (io.ReadCloser, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimPrefix(u.Path, "/")
+	if name == "" {
+		name = strings.TrimPrefix(u.Opaque, "/")
+	}
+	return l.fsys.Open(name)
+}
+
+// MapLoader is an in-memory Loader of schema documents keyed by URI.
+type MapLoader map[string][]byte
+
+// Load -
+func (l MapLoader) Load(uri string) (io.ReadCloser, error) {
+	doc, ok := l[uri]
+	if !ok {
+		return nil, fmt.Errorf("schema %q not found", uri)
+	}
+	return io.NopCloser(bytes.NewReader(doc)), nil
+}
+
+// WithLoader -
+func WithLoader(l Loader) Option {
+	return func(v *Validator) {
+		v.loader = l
+	}
+}
@@ -0,0 +1,302 @@
+package jsonschema
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// DefaultLocale is the locale of messages when none is picked, and the
+// locale of messages missing from other locales.
+const DefaultLocale = "en"
+
+// builtinMessages are the message templates shipped with the package, by
+// locale and code.
+var builtinMessages = map[string]map[string]string{
+	"en": {
+		CodeInvalidJSON:   "Invalid JSON: {{.error}}",
+		CodeInvalidValue:  "Value is not JSON: {{.error}}",
+		CodeInvalidNumber: "Invalid number: {{.actual}}",
+		CodeFalseSchema:   "Value is not allowed",
+
+		CodeRequired: "{{if .required}}Missing required property: {{.required}}{{else}}Missing required field{{end}}",
+		CodeNotNull:  "Required field must not be null",
+		CodeNotZero:  "Required field must not be zero",
+
+		CodeType:   `Invalid type, expected {{join .expected " or "}} but got {{.actual}}`,
+		CodeFormat: "Format validation failed ({{.error}})",
+		CodeConst:  "Value {{json .actual}} does not match const {{json .const}}",
+		CodeEnum:   "No enum match for: {{json .actual}}",
+
+		CodeAnyOf:         "Does not match any schema of anyOf",
+		CodeOneOf:         "Does not match any schema of oneOf",
+		CodeOneOfMultiple: "Matches more than one schema of oneOf (indices {{index .indices 0}} and {{index .indices 1}})",
+		CodeNot:           "Matches the schema of not",
+		CodeThen:          "Does not match the schema of then",
+		CodeElse:          "Does not match the schema of else",
+
+		CodeMinimum:          "Value {{.actual}} is less than minimum {{.limit}}",
+		CodeMaximum:          "Value {{.actual}} is greater than maximum {{.limit}}",
+		CodeExclusiveMinimum: "Value {{.actual}} is equal to exclusive minimum {{.limit}}",
+		CodeExclusiveMaximum: "Value {{.actual}} is equal to exclusive maximum {{.limit}}",
+		CodeMultipleOf:       "Value {{.actual}} is not a multiple of {{.multipleOf}}",
+
+		CodeMinLength: "String is too short ({{.actual}} chars), minimum {{.limit}}",
+		CodeMaxLength: "String is too long ({{.actual}} chars), maximum {{.limit}}",
+		CodePattern:   "String does not match pattern: {{.pattern}}",
+
+		CodeMinItems:        "Array is too short ({{.actual}}), minimum {{.limit}}",
+		CodeMaxItems:        "Array is too long ({{.actual}}), maximum {{.limit}}",
+		CodeUniqueItems:     "Array items are not unique (indices {{index .indices 0}} and {{index .indices 1}})",
+		CodeAdditionalItems: "Additional item {{.index}} is not allowed",
+		CodeContains:        "Item {{.index}} does not match the schema of contains",
+		CodeContainsMatch:   "Item {{.index}} matches the schema of contains",
+		CodeMinContains:     "Array contains too few matching items ({{.actual}}), minimum {{.limit}}",
+		CodeMaxContains:     "Array contains too many matching items ({{.actual}}), maximum {{.limit}}",
+
+		CodeMinProperties:        "Too few properties defined ({{.actual}}), minimum {{.limit}}",
+		CodeMaxProperties:        "Too many properties defined ({{.actual}}), maximum {{.limit}}",
+		CodeAdditionalProperties: "Additional property {{.property}} is not allowed",
+		CodePropertyPattern:      "Properties does not match pattern: {{.pattern}}",
+		CodeDependentRequired:    "Missing property {{.property}} required by {{.dependent}}",
+	},
+	"ja": {
+		CodeInvalidJSON:   "JSONが不正です: {{.error}}",
+		CodeInvalidValue:  "値をJSONに変換できません: {{.error}}",
+		CodeInvalidNumber: "数値が不正です: {{.actual}}",
+		CodeFalseSchema:   "値は許可されていません",
+
+		CodeRequired: "{{if .required}}必須のプロパティがありません: {{.required}}{{else}}必須のフィールドがありません{{end}}",
+		CodeNotNull:  "必須のフィールドはnullにできません",
+		CodeNotZero:  "必須のフィールドはゼロ値にできません",
+
+		CodeType:   `型が不正です。{{join .expected "または"}}が必要ですが{{.actual}}です`,
+		CodeFormat: "フォーマットの検証に失敗しました ({{.error}})",
+		CodeConst:  "値{{json .actual}}が定数{{json .const}}と一致しません",
+		CodeEnum:   "列挙値に一致しません: {{json .actual}}",
+
+		CodeAnyOf:         "anyOfのどのスキーマにも一致しません",
+		CodeOneOf:         "oneOfのどのスキーマにも一致しません",
+		CodeOneOfMultiple: "oneOfの複数のスキーマに一致します (インデックス{{index .indices 0}}と{{index .indices 1}})",
+		CodeNot:           "notのスキーマに一致します",
+		CodeThen:          "thenのスキーマに一致しません",
+		CodeElse:          "elseのスキーマに一致しません",
+
+		CodeMinimum:          "値{{.actual}}は最小値{{.limit}}より小さいです",
+		CodeMaximum:          "値{{.actual}}は最大値{{.limit}}より大きいです",
+		CodeExclusiveMinimum: "値{{.actual}}は{{.limit}}より大きくなければなりません",
+		CodeExclusiveMaximum: "値{{.actual}}は{{.limit}}より小さくなければなりません",
+		CodeMultipleOf:       "値{{.actual}}は{{.multipleOf}}の倍数ではありません",
+
+		CodeMinLength: "文字列が短すぎます ({{.actual}}文字)。最小{{.limit}}文字です",
+		CodeMaxLength: "文字列が長すぎます ({{.actual}}文字)。最大{{.limit}}文字です",
+		CodePattern:   "文字列がパターンに一致しません: {{.pattern}}",
+
+		CodeMinItems:        "配列の要素が少なすぎます ({{.actual}})。最小{{.limit}}です",
+		CodeMaxItems:        "配列の要素が多すぎます ({{.actual}})。最大{{.limit}}です",
+		CodeUniqueItems:     "配列の要素が重複しています (インデックス{{index .indices 0}}と{{index .indices 1}})",
+		CodeAdditionalItems: "要素{{.index}}は許可されていません",
+		CodeContains:        "要素{{.index}}はcontainsのスキーマに一致しません",
+		CodeContainsMatch:   "要素{{.index}}はcontainsのスキーマに一致します",
+		CodeMinContains:     "配列にcontainsに一致する要素が少なすぎます ({{.actual}})。最小{{.limit}}です",
+		CodeMaxContains:     "配列にcontainsに一致する要素が多すぎます ({{.actual}})。最大{{.limit}}です",
+
+		CodeMinProperties:        "プロパティが少なすぎます ({{.actual}})。最小{{.limit}}です",
+		CodeMaxProperties:        "プロパティが多すぎます ({{.actual}})。最大{{.limit}}です",
+		CodeAdditionalProperties: "プロパティ{{.property}}は許可されていません",
+		CodePropertyPattern:      "プロパティ名がパターンに一致しません: {{.pattern}}",
+		CodeDependentRequired:    "{{.dependent}}があるときはプロパティ{{.property}}が必要です",
+	},
+}
+
+var messageFuncs = template.FuncMap{
+	"join": strings.Join,
+	"json": formatJSON,
+}
+
+// message is a parsed message template. Templates of text and fields of the
+// params alone are rendered from parts, text/template costs more than the
+// rest of a validation.
+type message struct {
+	t     *template.Template
+	parts []messagePart // nil if the template does more than print fields
+}
+
+// messagePart is either text or the name of a param.
+type messagePart struct {
+	text  string
+	field string
+}
+
+func newMessage(t *template.Template) *message {
+	m := &message{t: t}
+	for _, node := range t.Tree.Root.Nodes {
+		switch n := node.(type) {
+		case *parse.TextNode:
+			m.parts = append(m.parts, messagePart{text: string(n.Text)})
+		case *parse.ActionNode:
+			field, ok := paramField(n.Pipe)
+			if !ok {
+				m.parts = nil
+				return m
+			}
+			m.parts = append(m.parts, messagePart{field: field})
+		default:
+			m.parts = nil
+			return m
+		}
+	}
+	return m
+}
+
+// paramField returns the name of the param pipe prints, as {{.name}} does.
+func paramField(pipe *parse.PipeNode) (string, bool) {
+	if len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return "", false
+	}
+	field, ok := pipe.Cmds[0].Args[0].(*parse.FieldNode)
+	if !ok || len(field.Ident) != 1 {
+		return "", false
+	}
+	return field.Ident[0], true
+}
+
+// catalogs are the parsed builtinMessages.
+var catalogs = func() map[string]map[string]*message {
+	catalogs := map[string]map[string]*message{}
+	for locale, messages := range builtinMessages {
+		catalog, err := parseMessages(messages)
+		if err != nil {
+			panic(err)
+		}
+		catalogs[locale] = catalog
+	}
+	return catalogs
+}()
+
+func parseMessages(messages map[string]string) (map[string]*message, error) {
+	catalog := make(map[string]*message, len(messages))
+	for code, text := range messages {
+		t, err := template.New(code).Funcs(messageFuncs).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("message %s: %s", code, err)
+		}
+		catalog[code] = newMessage(t)
+	}
+	return catalog, nil
+}
+
+// AddMessages registers message templates for locale by code, replacing
+// the built-in ones. Templates are text/template templates of the Params
+// of the error, with the functions join and json. Messages of a format
+// added with AddFormat go under "format:" and the format name.
+func (v *Validator) AddMessages(locale string, messages map[string]string) error {
+	catalog, err := parseMessages(messages)
+	if err != nil {
+		return err
+	}
+	if v.messages == nil {
+		v.messages = map[string]map[string]*message{}
+	}
+	if v.messages[locale] == nil {
+		v.messages[locale] = map[string]*message{}
+	}
+	for code, t := range catalog {
+		v.messages[locale][code] = t
+	}
+	return nil
+}
+
+// WithLocale -
+func WithLocale(locale string) Option {
+	return func(v *Validator) {
+		v.locale = locale
+	}
+}
+
+type localeKey struct{}
+
+// ContextWithLocale returns a context picking the locale of the messages of
+// ValidateContext.
+func ContextWithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+func (v *Validator) localeOf(ctx context.Context) string {
+	if locale, ok := ctx.Value(localeKey{}).(string); ok && locale != "" {
+		return locale
+	}
+	if v.locale != "" {
+		return v.locale
+	}
+	return DefaultLocale
+}
+
+// message returns the template of the first of codes found in the messages
+// added for locale or the built-in ones, and then in the default locale.
+func (v *Validator) message(locale string, codes ...string) *message {
+	for _, l := range []string{locale, DefaultLocale} {
+		for _, code := range codes {
+			if t, ok := v.messages[l][code]; ok {
+				return t
+			}
+			if t, ok := catalogs[l][code]; ok {
+				return t
+			}
+		}
+	}
+	return nil
+}
+
+// localize sets the messages of err and its causes.
+func (v *Validator) localize(err error, locale string) error {
+	ret, ok := err.(*ValidationError)
+	if !ok || ret == nil {
+		return err
+	}
+	v.localizeError(ret, locale)
+	return ret
+}
+
+func (v *Validator) localizeError(e *ValidationError, locale string) {
+	if e.Code != "" {
+		codes := []string{e.Code}
+		if format, ok := e.Params["format"].(string); ok && e.Code == CodeFormat {
+			codes = []string{CodeFormat + ":" + format, CodeFormat}
+		}
+		e.Message = render(v.message(locale, codes...), e.Code, e.Params)
+	}
+	for _, c := range e.Causes {
+		v.localizeError(c, locale)
+	}
+}
+
+func render(m *message, code string, params map[string]interface{}) string {
+	if m == nil {
+		return code
+	}
+	if m.parts != nil {
+		var buf strings.Builder
+		for _, part := range m.parts {
+			if part.field == "" {
+				buf.WriteString(part.text)
+			} else if value := params[part.field]; value != nil {
+				fmt.Fprint(&buf, value)
+			} else {
+				buf.WriteString("<no value>")
+			}
+		}
+		return buf.String()
+	}
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	buf := &bytes.Buffer{}
+	if err := m.t.Execute(buf, params); err != nil {
+		return code
+	}
+	return buf.String()
+}
@@ -0,0 +1,120 @@
+package jsonschema
+
+import "encoding/json"
+
+// OutputFormat is one of the output formats of JSON Schema 2019-09.
+type OutputFormat int
+
+// Output formats
+const (
+	// OutputFlag only tells whether the value is valid
+	OutputFlag OutputFormat = iota
+	// OutputBasic lists every error
+	OutputBasic
+	// OutputDetailed nests the errors of keywords with sub-schemas, such as
+	// anyOf, under them
+	OutputDetailed
+	// OutputVerbose keeps every node of the Causes tree
+	OutputVerbose
+)
+
+// Output is an output unit of JSON Schema 2019-09. The outermost unit of
+// the flag and basic formats has no locations.
+type Output struct {
+	Valid            bool
+	KeywordLocation  string
+	InstanceLocation string
+	Error            string
+	Errors           []*Output
+
+	located bool
+}
+
+// MarshalJSON -
+func (o Output) MarshalJSON() ([]byte, error) {
+	type unit struct {
+		Valid            bool      `json:"valid"`
+		KeywordLocation  *string   `json:"keywordLocation,omitempty"`
+		InstanceLocation *string   `json:"instanceLocation,omitempty"`
+		Error            string    `json:"error,omitempty"`
+		Errors           []*Output `json:"errors,omitempty"`
+	}
+	u := unit{Valid: o.Valid, Error: o.Error, Errors: o.Errors}
+	if o.located {
+		u.KeywordLocation, u.InstanceLocation = &o.KeywordLocation, &o.InstanceLocation
+	}
+	return json.Marshal(u)
+}
+
+// Format returns the error in the output format f. A nil error is valid.
+func (v *ValidationError) Format(f OutputFormat) *Output {
+	if v == nil || v.isEmpty() {
+		return &Output{Valid: true}
+	}
+	switch f {
+	case OutputFlag:
+		return &Output{}
+	case OutputBasic:
+		out := &Output{}
+		v.walk(func(e *ValidationError) {
+			out.Errors = append(out.Errors, e.output())
+		})
+		return out
+	case OutputDetailed:
+		return &Output{Errors: v.detailed(), located: true}
+	}
+	return v.verbose(&Output{})
+}
+
+// MarshalJSON encodes the error in the basic output format.
+func (v *ValidationError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.Format(OutputBasic))
+}
+
+// walk calls f with every error of the tree holding a message, parents
+// first.
+func (v *ValidationError) walk(f func(*ValidationError)) {
+	if v.Code != "" || v.Message != "" {
+		f(v)
+	}
+	for _, c := range v.Causes {
+		c.walk(f)
+	}
+}
+
+func (v *ValidationError) output() *Output {
+	return &Output{
+		KeywordLocation:  v.KeywordLocation,
+		InstanceLocation: v.InstancePath,
+		Error:            v.Message,
+		located:          true,
+	}
+}
+
+// detailed returns the units of the errors of the tree. Nodes only grouping
+// causes are left out, their causes take their place.
+func (v *ValidationError) detailed() []*Output {
+	var errs []*Output
+	for _, c := range v.Causes {
+		errs = append(errs, c.detailed()...)
+	}
+	if v.Code == "" && v.Message == "" {
+		return errs
+	}
+	out := v.output()
+	out.Errors = errs
+	return []*Output{out}
+}
+
+// verbose returns the unit of every node of the tree. Nodes only grouping
+// causes have the locations of their parent.
+func (v *ValidationError) verbose(parent *Output) *Output {
+	out := v.output()
+	if v.Code == "" && v.Message == "" {
+		out.KeywordLocation, out.InstanceLocation = parent.KeywordLocation, parent.InstanceLocation
+	}
+	for _, c := range v.Causes {
+		out.Errors = append(out.Errors, c.verbose(out))
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+package jsonschema
+
+import (
+	"reflect"
+	"unicode"
+)
+
+// structPlan is the compiled form of a struct type. It is built once per
+// reflect.Type and must not be modified afterwards, so it can be shared
+// between goroutines.
+type structPlan struct {
+	fields []fieldPlan
+	tags   []*tag         // struct-level tags of blank "_" fields
+	names  map[string]int // json and Go field names -> field index
+}
+
+type fieldPlan struct {
+	index int
+	name  string
+	token string // json name, the token of the field in instance paths
+	field reflect.StructField
+	// omitempty is set when encoding/json omits the field when empty
+	omitempty bool
+	tag       *tag
+}
+
+type planEntry struct {
+	plan *structPlan
+	err  error
+}
+
+func (v *Validator) plan(rt reflect.Type) (*structPlan, error) {
+	if e, ok := v.plans.Load(rt); ok {
+		entry := e.(*planEntry)
+		return entry.plan, entry.err
+	}
+	plan, err := v.compile(rt)
+	e, _ := v.plans.LoadOrStore(rt, &planEntry{plan: plan, err: err})
+	entry := e.(*planEntry)
+	return entry.plan, entry.err
+}
+
+func (v *Validator) compile(rt reflect.Type) (*structPlan, error) {
+	plan := &structPlan{
+		fields: make([]fieldPlan, 0, rt.NumField()),
+		names:  map[string]int{},
+	}
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name := field.Name
+		if name == "_" {
+			if tagValue := field.Tag.Get(tagName); tagValue != "" && tagValue != "-" {
+				t, err := v.parseTag(tagValue)
+				if err != nil {
+					err.Type, err.Field = rt.String(), name
+					return nil, err
+				}
+				plan.tags = append(plan.tags, t)
+			}
+			continue
+		}
+		if jsonName, _, ok := jsonName(field); ok {
+			if _, exists := plan.names[name]; !exists {
+				plan.names[name] = i
+			}
+			if jsonName != "" {
+				plan.names[jsonName] = i
+			}
+		}
+		tagValue, ok := lookupTag(field)
+		if !ok {
+			continue
+		}
+
+		var tag *tag
+		if tagValue != "" {
+			t, err := v.parseTag(tagValue)
+			if err != nil {
+				err.Type, err.Field = rt.String(), name
+				return nil, err
+			}
+			tag = t
+		}
+
+		_, omitempty, _ := jsonName(field)
+		plan.fields = append(plan.fields, fieldPlan{
+			index:     i,
+			name:      name,
+			token:     fieldToken(field),
+			field:     field,
+			omitempty: omitempty,
+			tag:       tag,
+		})
+	}
+	return plan, nil
+}
+
+// fieldToken returns the name of a struct field in its JSON encoding.
+func fieldToken(field reflect.StructField) string {
+	if name, _, _ := jsonName(field); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// lookupTag returns the jsonschema tag of a field, and false if the field is
+// not validated at all.
+func lookupTag(field reflect.StructField) (string, bool) {
+	if !unicode.IsUpper(rune(field.Name[0])) {
+		return "", false
+	}
+	tagValue := field.Tag.Get(tagName)
+	if tagValue == "-" {
+		return "", false
+	}
+	return tagValue, true
+}
@@ -0,0 +1,65 @@
+package jsonschema
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped tokens.
+func parsePointer(ptr string) ([]string, error) {
+	if ptr == "" {
+		return nil, nil
+	}
+	if ptr[0] != '/' {
+		return nil, errors.New("json pointer must start with '/'")
+	}
+	tokens := strings.Split(ptr[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] != '~' {
+				continue
+			}
+			if j == len(token)-1 || (token[j+1] != '0' && token[j+1] != '1') {
+				return nil, fmt.Errorf("invalid escape in json pointer token %q", token)
+			}
+		}
+		tokens[i] = unescapePointer(token)
+	}
+	return tokens, nil
+}
+
+// resolvePointer evaluates pointer tokens against a decoded JSON document.
+func resolvePointer(doc interface{}, tokens []string) (interface{}, error) {
+	node := doc
+	for _, token := range tokens {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			value, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("json pointer token %q not found", token)
+			}
+			node = value
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(n) || (len(token) > 1 && token[0] == '0') {
+				return nil, fmt.Errorf("invalid array index %q in json pointer", token)
+			}
+			node = n[i]
+		default:
+			return nil, fmt.Errorf("json pointer token %q not found", token)
+		}
+	}
+	return node, nil
+}
+
+func escapePointer(token string) string {
+	token = strings.Replace(token, "~", "~0", -1)
+	return strings.Replace(token, "/", "~1", -1)
+}
+
+func unescapePointer(token string) string {
+	token = strings.Replace(token, "~1", "/", -1)
+	return strings.Replace(token, "~0", "~", -1)
+}
@@ -0,0 +1,6 @@
+//go:build race
+
+package jsonschema
+
+// raceEnabled is set in race builds, where sync.Pool drops items at random.
+const raceEnabled = true
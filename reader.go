@@ -1,63 +1,258 @@
 package jsonschema
 
 import (
-	"bytes"
-	"encoding/binary"
+	"errors"
+	"strings"
 )
 
 type reader struct {
-	buf *bytes.Reader
+	buf []byte
 	pos int
 	max int
 }
 
 func newReader(b []byte) *reader {
-	return &reader{buf: bytes.NewReader(b), pos: 0, max: len(b)}
+	return &reader{buf: b, pos: 0, max: len(b)}
 }
 
 func (r *reader) IsEOF() bool {
 	return r.pos >= r.max
 }
 
+func (r *reader) PeekByte() (byte, bool) {
+	if r.IsEOF() {
+		return 0, false
+	}
+	return r.buf[r.pos], true
+}
+
 func (r *reader) ReadByte() (byte, error) {
-	var value byte
-	if err := binary.Read(r.buf, binary.BigEndian, &value); err != nil {
-		return 0, err
+	if r.IsEOF() {
+		return 0, errors.New("unexpected end of tag")
 	}
+	b := r.buf[r.pos]
 	r.pos++
-	return value, nil
+	return b, nil
 }
 
-func (r *reader) ReadBytes(num int) []byte {
-	value := make([]byte, num)
-	if err := binary.Read(r.buf, binary.BigEndian, value); err != nil {
-		panic(err)
+func (r *reader) SkipSpaces() {
+	for !r.IsEOF() && valueIs(r.buf[r.pos], ' ', '\t') {
+		r.pos++
 	}
-	r.pos += num
-	return value
 }
 
-func (r *reader) ReadSeparator() string {
-	buf := make([]byte, 0, 16)
-	for {
-		b, _ := r.ReadByte()
-		if b == byte(',') {
+// ReadKeyword reads an identifier made of ASCII letters.
+func (r *reader) ReadKeyword() string {
+	start := r.pos
+	for !r.IsEOF() {
+		b := r.buf[r.pos]
+		if !(b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z') {
 			break
 		}
-		buf = append(buf, b)
-		if r.IsEOF() {
-			break
+		r.pos++
+	}
+	return string(r.buf[start:r.pos])
+}
+
+// ReadValue reads a value up to the next ',' outside of quotes and
+// brackets, and returns it trimmed with its quotes. Bare values may hold
+// commas within balanced (), [] and {}, as in "^[a-z]{1,3}$"; a backslash
+// keeps the next byte from counting.
+func (r *reader) ReadValue() (string, error) {
+	r.SkipSpaces()
+	start := r.pos
+	if b, ok := r.PeekByte(); ok && valueIs(b, '"', '\'') {
+		if err := r.skipQuoted(); err != nil {
+			return "", err
+		}
+		return string(r.buf[start:r.pos]), nil
+	}
+	depth := 0
+	for !r.IsEOF() {
+		b := r.buf[r.pos]
+		switch {
+		case b == '\\':
+			r.pos++
+		case b == '"' && depth > 0 && r.quoteStarts(start):
+			// a JSON string of an array or object literal
+			if err := r.skipQuoted(); err != nil {
+				return "", err
+			}
+			continue
+		case valueIs(b, '(', '[', '{'):
+			depth++
+		case valueIs(b, ')', ']', '}') && depth > 0:
+			depth--
+		case b == ',' && depth == 0:
+			return strings.TrimSpace(string(r.buf[start:r.pos])), nil
+		}
+		r.pos++
+	}
+	if r.pos > r.max {
+		r.pos = r.max
+	}
+	if depth > 0 {
+		return "", errors.New("unbalanced brackets, quote the value")
+	}
+	return strings.TrimSpace(string(r.buf[start:r.pos])), nil
+}
+
+// ReadString reads a value and returns it unquoted, and whether it was
+// quoted.
+func (r *reader) ReadString() (string, bool, error) {
+	raw, err := r.ReadValue()
+	if err != nil {
+		return "", false, err
+	}
+	value, quoted := unquote(raw)
+	return value, quoted, nil
+}
+
+// skipQuoted skips a string quoted with ' or ".
+func (r *reader) skipQuoted() error {
+	q := r.buf[r.pos]
+	r.pos++
+	for !r.IsEOF() {
+		b := r.buf[r.pos]
+		r.pos++
+		switch b {
+		case '\\':
+			r.pos++
+		case q:
+			return nil
+		}
+	}
+	r.pos = r.max
+	return errors.New("unterminated string")
+}
+
+// quoteStarts reports whether a quote at the current position opens a
+// string. Quotes only do at the start of a value, so that bare values may
+// hold them.
+func (r *reader) quoteStarts(from int) bool {
+	for i := r.pos - 1; i >= from; i-- {
+		if !valueIs(r.buf[i], ' ', '\t') {
+			return valueIs(r.buf[i], ':', '=', ',', '[', '(', '{')
 		}
 	}
-	return string(buf)
+	return true
 }
 
-func (r *reader) SkipDelimiter() {
+// unquote returns s without its quotes. A backslash escapes the quote and
+// itself only, other backslashes are kept for regexps.
+func unquote(s string) (string, bool) {
+	if len(s) < 2 || !valueIs(s[0], '"', '\'') || s[len(s)-1] != s[0] {
+		return s, false
+	}
+	q := s[0]
+	s = s[1 : len(s)-1]
+	if strings.IndexByte(s, '\\') < 0 {
+		return s, true
+	}
+	buf := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && valueIs(s[i+1], q, '\\') {
+			i++
+		}
+		buf = append(buf, s[i])
+	}
+	return string(buf), true
+}
+
+// ReadName reads a name up to the next '(' or delimiter and trims the
+// surrounding spaces. Quoted names are unquoted.
+func (r *reader) ReadName() string {
+	r.SkipSpaces()
+	if b, ok := r.PeekByte(); ok && valueIs(b, '"', '\'') {
+		start := r.pos
+		if r.skipQuoted() != nil {
+			return ""
+		}
+		name, _ := unquote(string(r.buf[start:r.pos]))
+		r.SkipSpaces()
+		return name
+	}
+	start := r.pos
+	for !r.IsEOF() && !valueIs(r.buf[r.pos], '(', ',', ':', '=') {
+		r.pos++
+	}
+	return strings.TrimSpace(string(r.buf[start:r.pos]))
+}
+
+// ReadList reads a bracketed list such as "[a,b,c]" and returns its items
+// trimmed with their quotes. Items may be quoted strings, and JSON arrays
+// and objects holding commas.
+func (r *reader) ReadList() ([]string, error) {
+	if b, err := r.ReadByte(); err != nil || b != '[' {
+		return nil, errors.New("expected '['")
+	}
+	list := []string{}
+	start := r.pos
+	depth := 0
 	for {
-		b, _ := r.ReadByte()
-		if !valueIs(b, byte(':'), byte('=')) {
+		b, ok := r.PeekByte()
+		if !ok {
+			return nil, errors.New("unterminated list, expected ']'")
+		}
+		if valueIs(b, '"', '\'') && r.quoteStarts(start) {
+			if err := r.skipQuoted(); err != nil {
+				return nil, err
+			}
 			continue
 		}
-		break
+		r.pos++
+		switch {
+		case b == '[' || b == '{':
+			depth++
+		case (b == ']' || b == '}') && depth > 0:
+			depth--
+		case b == ',' && depth == 0:
+			list = append(list, strings.TrimSpace(string(r.buf[start:r.pos-1])))
+			start = r.pos
+		case b == ']':
+			list = append(list, strings.TrimSpace(string(r.buf[start:r.pos-1])))
+			return list, nil
+		}
+	}
+}
+
+func (r *reader) SkipDelimiter() error {
+	b, ok := r.PeekByte()
+	if !ok || !valueIs(b, byte(':'), byte('=')) {
+		return errors.New("expected ':' or '=' after keyword")
+	}
+	r.pos++
+	return nil
+}
+
+// ReadGroup reads a parenthesized group such as "(maxLength:5)" and returns a
+// reader over its content. Backslashes escape the next byte.
+func (r *reader) ReadGroup() (*reader, error) {
+	if b, ok := r.PeekByte(); !ok || b != '(' {
+		return nil, errors.New("expected '('")
+	}
+	start := r.pos + 1
+	depth := 0
+	for !r.IsEOF() {
+		b := r.buf[r.pos]
+		if valueIs(b, '"', '\'') && depth > 0 && r.quoteStarts(start) {
+			if err := r.skipQuoted(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		r.pos++
+		switch b {
+		case '\\':
+			r.pos++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return &reader{buf: r.buf, pos: start, max: r.pos - 1}, nil
+			}
+		}
 	}
+	return nil, errors.New("unterminated group, expected ')'")
 }
@@ -0,0 +1,313 @@
+package jsonschema
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+)
+
+// TagErrors -
+type TagErrors []*TagError
+
+// Error -
+func (e TagErrors) Error() string {
+	buf := bytes.NewBuffer(nil)
+	for i, err := range e {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(err.Error())
+	}
+	return buf.String()
+}
+
+// Unwrap -
+func (e TagErrors) Unwrap() error {
+	return ErrTagSyntax
+}
+
+// Register walks the given types recursively and reports every invalid
+// jsonschema tag: syntax errors, unknown formats, keywords that do not apply
+// to the field kind and lower bounds greater than upper bounds. Types without
+// errors are compiled and cached for later Validate calls.
+func (v *Validator) Register(types ...interface{}) error {
+	errs := TagErrors{}
+	seen := map[reflect.Type]bool{}
+	for _, t := range types {
+		if rt := reflect.TypeOf(t); rt != nil {
+			errs = v.checkType(rt, seen, errs)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// MustRegister -
+func (v *Validator) MustRegister(types ...interface{}) {
+	if err := v.Register(types...); err != nil {
+		panic(err)
+	}
+}
+
+func (v *Validator) checkType(rt reflect.Type, seen map[reflect.Type]bool, errs TagErrors) TagErrors {
+	for {
+		switch rt.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			rt = rt.Elem()
+			continue
+		case reflect.Map:
+			errs = v.checkType(rt.Key(), seen, errs)
+			rt = rt.Elem()
+			continue
+		}
+		break
+	}
+	if rt.Kind() != reflect.Struct || seen[rt] {
+		return errs
+	}
+	seen[rt] = true
+
+	n := len(errs)
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if field.Name == "_" {
+			// struct-level tag
+			if tagValue := field.Tag.Get(tagName); tagValue != "" && tagValue != "-" {
+				tag, err := v.parseTag(tagValue)
+				if err != nil {
+					err.Type, err.Field = rt.String(), field.Name
+					errs = append(errs, err)
+					continue
+				}
+				for _, err := range v.checkTag(tag, rt) {
+					err.Type, err.Field = rt.String(), field.Name
+					errs = append(errs, err)
+				}
+			}
+			continue
+		}
+		tagValue, ok := lookupTag(field)
+		if !ok {
+			continue
+		}
+		if tagValue != "" {
+			tag, err := v.parseTag(tagValue)
+			if err != nil {
+				err.Type, err.Field = rt.String(), field.Name
+				errs = append(errs, err)
+			} else {
+				for _, err := range v.checkTag(tag, field.Type) {
+					err.Type, err.Field = rt.String(), field.Name
+					errs = append(errs, err)
+				}
+			}
+		}
+		errs = v.checkType(field.Type, seen, errs)
+	}
+	if len(errs) == n {
+		v.plan(rt)
+	}
+	return errs
+}
+
+func (v *Validator) checkTag(t *tag, rt reflect.Type) []*TagError {
+	errs := []*TagError{}
+	fits := fitsKind
+	if t.items != nil {
+		// the elements have their own tag
+		fits = fitsOwnKind
+	}
+	for _, kw := range t.keywords {
+		if kw.name == "required" && t.fieldRequired {
+			continue
+		}
+		if !fits(rt, tagKeywords[kw.name]) {
+			errs = append(errs, &TagError{
+				Keyword: kw.name,
+				Offset:  kw.offset,
+				Reason:  fmt.Sprintf("keyword does not apply to %s", rt.String()),
+			})
+		}
+		if kw.name == "type" && !matchStaticType(t.types, rt) {
+			errs = append(errs, &TagError{
+				Keyword: kw.name,
+				Offset:  kw.offset,
+				Reason:  fmt.Sprintf("type %v never matches %s", t.types, rt.String()),
+			})
+		}
+		if kw.name == "format" {
+			if _, ok := v.formats[*t.format]; !ok {
+				errs = append(errs, &TagError{
+					Keyword: kw.name,
+					Offset:  kw.offset,
+					Reason:  fmt.Sprintf("unknown format %q", *t.format),
+				})
+			}
+		}
+	}
+
+	for _, sub := range t.applicators() {
+		errs = append(errs, v.checkTag(sub, rt)...)
+	}
+	for _, sub := range append([]*tag{t.items, t.contains}, t.prefixItems...) {
+		if sub != nil {
+			errs = append(errs, v.checkTag(sub, elemType(rt))...)
+		}
+	}
+	if rt := mapType(rt); rt != nil {
+		if len(t.required) > 0 && rt.Key().Kind() != reflect.String {
+			errs = append(errs, &TagError{
+				Keyword: "required",
+				Offset:  t.offset("required"),
+				Reason:  fmt.Sprintf("keys of %s are not strings", rt.String()),
+			})
+		}
+		if t.keys != nil {
+			errs = append(errs, v.checkTag(t.keys, rt.Key())...)
+		}
+		for _, sub := range []*tag{t.values, t.additionalProperties} {
+			if sub != nil {
+				errs = append(errs, v.checkTag(sub, rt.Elem())...)
+			}
+		}
+		for _, p := range t.patterns {
+			errs = append(errs, v.checkTag(p.tag, rt.Elem())...)
+		}
+	}
+	if rt.Kind() == reflect.Struct {
+		for _, name := range t.required {
+			if _, ok := propertyType(rt, name); !ok {
+				errs = append(errs, &TagError{
+					Keyword: "required",
+					Offset:  t.offset("required"),
+					Reason:  fmt.Sprintf("unknown property %q of %s", name, rt.String()),
+				})
+			}
+		}
+	}
+	for _, p := range t.properties {
+		ft, ok := propertyType(rt, p.name)
+		if !ok {
+			errs = append(errs, &TagError{
+				Keyword: "properties",
+				Offset:  t.offset("properties"),
+				Reason:  fmt.Sprintf("unknown property %q of %s", p.name, rt.String()),
+			})
+			continue
+		}
+		errs = append(errs, v.checkTag(p.tag, ft)...)
+	}
+
+	bound := func(min, max string, greater bool, minValue, maxValue interface{}) {
+		if greater {
+			errs = append(errs, &TagError{
+				Keyword: min,
+				Offset:  t.offset(min),
+				Reason:  fmt.Sprintf("%s %v is greater than %s %v", min, minValue, max, maxValue),
+			})
+		}
+	}
+	if t.minimum != nil && t.maximum != nil {
+		bound("minimum", "maximum", t.minimum.Cmp(t.maximum) > 0, formatNumber(t.minimum), formatNumber(t.maximum))
+	}
+	if t.minLength != nil && t.maxLength != nil {
+		bound("minLength", "maxLength", *t.minLength > *t.maxLength, *t.minLength, *t.maxLength)
+	}
+	if t.minItems != nil && t.maxItems != nil {
+		bound("minItems", "maxItems", *t.minItems > *t.maxItems, *t.minItems, *t.maxItems)
+	}
+	if t.minContains != nil && t.maxContains != nil {
+		bound("minContains", "maxContains", *t.minContains > *t.maxContains, *t.minContains, *t.maxContains)
+	}
+	if t.minProperties != nil && t.maxProperties != nil {
+		bound("minProperties", "maxProperties", *t.minProperties > *t.maxProperties, *t.minProperties, *t.maxProperties)
+	}
+	return errs
+}
+
+// fitsKind reports whether a keyword applicable to k can be used on a field
+// of type rt. Tags on slices and arrays also apply to their elements.
+func fitsKind(rt reflect.Type, k kinds) bool {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt == rawMessageType {
+		// validated as the JSON it holds
+		return true
+	}
+	if typeKinds(rt)&k != 0 {
+		return true
+	}
+	if rt.Kind() == reflect.Slice || rt.Kind() == reflect.Array {
+		return fitsKind(rt.Elem(), k)
+	}
+	return false
+}
+
+// fitsOwnKind reports whether a keyword applicable to k can be used on a
+// field of type rt itself.
+func fitsOwnKind(rt reflect.Type, k kinds) bool {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	return typeKinds(rt)&k != 0
+}
+
+// mapType returns the map type of a field of type rt, or of its elements
+// when rt is a slice or array, or nil.
+func mapType(rt reflect.Type) reflect.Type {
+	for {
+		switch rt.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			rt = rt.Elem()
+		case reflect.Map:
+			return rt
+		default:
+			return nil
+		}
+	}
+}
+
+// elemType returns the type of the elements of the slice or array rt.
+func elemType(rt reflect.Type) reflect.Type {
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	if rt.Kind() == reflect.Slice || rt.Kind() == reflect.Array {
+		return rt.Elem()
+	}
+	return rt
+}
+
+// propertyType returns the type of the property name of values of type rt.
+// Struct properties are looked up by json name and by field name.
+func propertyType(rt reflect.Type, name string) (reflect.Type, bool) {
+	for {
+		switch rt.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			rt = rt.Elem()
+			continue
+		case reflect.Map:
+			return rt.Elem(), true
+		case reflect.Struct:
+			var ft reflect.Type
+			for i := 0; i < rt.NumField(); i++ {
+				field := rt.Field(i)
+				jsonName, _, ok := jsonName(field)
+				if !ok {
+					continue
+				}
+				if jsonName == name {
+					return field.Type, true
+				}
+				if field.Name == name && ft == nil {
+					ft = field.Type
+				}
+			}
+			return ft, ft != nil
+		}
+		return rt, true
+	}
+}
@@ -0,0 +1,790 @@
+package jsonschema
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrSchema -
+	ErrSchema = errors.New("invalid schema")
+)
+
+// SchemaError -
+type SchemaError struct {
+	URI     string // document URI, empty for a document compiled from bytes
+	Pointer string // JSON Pointer of the schema holding the keyword
+	Keyword string
+	Reason  string
+}
+
+// Error -
+func (e *SchemaError) Error() string {
+	if e.Keyword == "" {
+		return fmt.Sprintf("%s at %s#%s: %s", ErrSchema.Error(), e.URI, e.Pointer, e.Reason)
+	}
+	return fmt.Sprintf("%s at %s#%s: %q: %s", ErrSchema.Error(), e.URI, e.Pointer, e.Keyword, e.Reason)
+}
+
+// Unwrap -
+func (e *SchemaError) Unwrap() error {
+	return ErrSchema
+}
+
+// Schema is a compiled JSON Schema document. It is immutable and safe for
+// concurrent use.
+type Schema struct {
+	v     *Validator
+	draft Draft
+	root  *tag
+}
+
+// Compile -
+func (v *Validator) Compile(doc []byte) (*Schema, error) {
+	return v.CompileReader(bytes.NewReader(doc))
+}
+
+// CompileReader -
+func (v *Validator) CompileReader(r io.Reader) (*Schema, error) {
+	return v.compileDocument("", r)
+}
+
+// CompileURI compiles the schema document the Loader returns for uri.
+func (v *Validator) CompileURI(uri string) (*Schema, error) {
+	if v.loader == nil {
+		return nil, &SchemaError{URI: uri, Reason: "no loader configured"}
+	}
+	r, err := v.loader.Load(uri)
+	if err != nil {
+		return nil, &SchemaError{URI: uri, Reason: err.Error()}
+	}
+	defer r.Close()
+	return v.compileDocument(uri, r)
+}
+
+func (v *Validator) compileDocument(uri string, r io.Reader) (*Schema, error) {
+	doc, err := decodeSchema(r)
+	if err != nil {
+		return nil, &SchemaError{URI: uri, Reason: err.Error()}
+	}
+
+	c := newCompiler(v)
+	if obj, ok := doc.(map[string]interface{}); ok && c.draft == 0 {
+		if s, ok := obj["$schema"].(string); ok {
+			c.draft = draftOf(s)
+		}
+	}
+	if err := c.addDocument(uri, doc); err != nil {
+		return nil, err
+	}
+	root, err := c.compileAt(location{doc: uri})
+	if err != nil {
+		return nil, err
+	}
+	if err := c.checkCycles(); err != nil {
+		return nil, err
+	}
+	return &Schema{v: v, draft: c.draft, root: root}, nil
+}
+
+func decodeSchema(r io.Reader) (interface{}, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Validate validates JSON data, given either as []byte, json.RawMessage or
+// as a value decoded by encoding/json.
+func (s *Schema) Validate(data interface{}) error {
+	return s.ValidateContext(context.Background(), data)
+}
+
+// ValidateContext validates JSON data like Validate, with the messages of
+// the locale picked by ContextWithLocale.
+func (s *Schema) ValidateContext(ctx context.Context, data interface{}) error {
+	switch raw := data.(type) {
+	case []byte:
+		return s.validateJSON(ctx, raw)
+	case json.RawMessage:
+		return s.validateJSON(ctx, raw)
+	}
+	return s.validate(ctx, data)
+}
+
+func (s *Schema) validateJSON(ctx context.Context, raw []byte) error {
+	data, err := decodeJSON(raw)
+	if err != nil {
+		return err
+	}
+	return s.validate(ctx, data)
+}
+
+func (s *Schema) validate(ctx context.Context, data interface{}) error {
+	st := &state{max: s.v.maxErrors}
+	err := st.done(s.v.validate(st, reflect.ValueOf(data), evalPath{}, nil, s.root))
+	ret, ok := err.(*ValidationError)
+	if ok && (ret == nil || ret.isEmpty()) {
+		return nil
+	}
+	return s.v.localize(err, s.v.localeOf(ctx))
+}
+
+func draftOf(uri string) Draft {
+	uri = strings.TrimSuffix(uri, "#")
+	for d, u := range draftURIs {
+		if strings.TrimSuffix(u, "#") == uri {
+			return d
+		}
+	}
+	return 0
+}
+
+// location identifies a schema by its document and JSON Pointer.
+type location struct {
+	doc string
+	ptr string
+}
+
+func (l location) child(tokens ...string) location {
+	ptr := l.ptr
+	for _, token := range tokens {
+		ptr += "/" + escapePointer(token)
+	}
+	return location{doc: l.doc, ptr: ptr}
+}
+
+type compiler struct {
+	draft     Draft
+	loader    Loader
+	docs      map[string]interface{} // document URI -> decoded document
+	resources map[string]location    // $id and anchor URIs
+	bases     map[location]*url.URL  // base URI of every schema object
+	tags      map[location]*tag
+}
+
+func newCompiler(v *Validator) *compiler {
+	return &compiler{
+		draft:     v.draft,
+		loader:    v.loader,
+		docs:      map[string]interface{}{},
+		resources: map[string]location{},
+		bases:     map[location]*url.URL{},
+		tags:      map[location]*tag{},
+	}
+}
+
+func (c *compiler) addDocument(uri string, doc interface{}) error {
+	base, err := url.Parse(uri)
+	if err != nil {
+		return &SchemaError{URI: uri, Reason: err.Error()}
+	}
+	c.docs[uri] = doc
+	c.resources[uri] = location{doc: uri}
+	return c.scan(doc, location{doc: uri}, base)
+}
+
+// scan registers the $id and anchors of every schema in a document.
+func (c *compiler) scan(node interface{}, loc location, base *url.URL) error {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if id := c.id(n); id != "" {
+			u, err := resolveURI(base, id)
+			if err != nil {
+				return &SchemaError{URI: loc.doc, Pointer: loc.ptr, Keyword: "$id", Reason: err.Error()}
+			}
+			if u.Fragment != "" && !strings.HasPrefix(u.Fragment, "/") {
+				// plain name fragment, the anchor of earlier drafts
+				c.resources[u.String()] = loc
+			} else {
+				u.Fragment = ""
+				base = u
+				c.resources[u.String()] = loc
+			}
+		}
+		if anchor, ok := n["$anchor"].(string); ok {
+			u := *base
+			u.Fragment = anchor
+			c.resources[u.String()] = loc
+		}
+		c.bases[loc] = base
+		for key, value := range n {
+			if key == "enum" || key == "const" {
+				continue
+			}
+			if err := c.scan(value, location{doc: loc.doc, ptr: loc.ptr + "/" + escapePointer(key)}, base); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, value := range n {
+			if err := c.scan(value, location{doc: loc.doc, ptr: loc.ptr + "/" + strconv.Itoa(i)}, base); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *compiler) id(obj map[string]interface{}) string {
+	if id, ok := obj["$id"].(string); ok && c.draft != Draft04 {
+		return id
+	}
+	if id, ok := obj["id"].(string); ok && (c.draft == 0 || c.draft == Draft04) {
+		return id
+	}
+	return ""
+}
+
+// resolveURI resolves ref against base. URL.Parse roots the paths it
+// resolves, which turns refs between documents without an absolute URI,
+// such as "defs.json" in "root.json", into "/defs.json".
+func resolveURI(base *url.URL, ref string) (*url.URL, error) {
+	u, err := base.Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+	relative := base.Scheme == "" && base.Host == "" && !strings.HasPrefix(base.Path, "/")
+	if relative && u.Scheme == "" && u.Host == "" && !strings.HasPrefix(ref, "/") {
+		u.Path = strings.TrimPrefix(u.Path, "/")
+	}
+	return u, nil
+}
+
+// resolve returns the location of the schema ref points to, loading the
+// referenced document if needed.
+func (c *compiler) resolve(ref string, base *url.URL) (location, error) {
+	u, err := resolveURI(base, ref)
+	if err != nil {
+		return location{}, err
+	}
+	if loc, ok := c.resources[u.String()]; ok && u.Fragment != "" {
+		return loc, nil
+	}
+	fragment := u.Fragment
+	u.Fragment = ""
+	uri := u.String()
+
+	loc, ok := c.resources[uri]
+	if !ok {
+		if c.loader == nil {
+			return location{}, fmt.Errorf("cannot resolve %q", u.String())
+		}
+		r, err := c.loader.Load(uri)
+		if err != nil {
+			return location{}, fmt.Errorf("cannot load %q: %s", uri, err)
+		}
+		doc, err := decodeSchema(r)
+		r.Close()
+		if err != nil {
+			return location{}, fmt.Errorf("cannot load %q: %s", uri, err)
+		}
+		if err := c.addDocument(uri, doc); err != nil {
+			return location{}, err
+		}
+		loc = c.resources[uri]
+	}
+	if fragment == "" {
+		return loc, nil
+	}
+	if !strings.HasPrefix(fragment, "/") {
+		u.Fragment = fragment
+		if loc, ok := c.resources[u.String()]; ok {
+			return loc, nil
+		}
+		return location{}, fmt.Errorf("cannot resolve anchor %q", ref)
+	}
+	loc.ptr += fragment
+	return loc, nil
+}
+
+// compileAt compiles the schema at loc. Schemas are compiled once, so
+// recursive references end up pointing at the same tag.
+func (c *compiler) compileAt(loc location) (*tag, error) {
+	if t, ok := c.tags[loc]; ok {
+		return t, nil
+	}
+	tokens, err := parsePointer(loc.ptr)
+	if err != nil {
+		return nil, &SchemaError{URI: loc.doc, Pointer: loc.ptr, Reason: err.Error()}
+	}
+	node, err := resolvePointer(c.docs[loc.doc], tokens)
+	if err != nil {
+		return nil, &SchemaError{URI: loc.doc, Pointer: loc.ptr, Reason: err.Error()}
+	}
+	t := newTag()
+	c.tags[loc] = t
+	if err := c.compile(t, node, loc); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// checkCycles returns an error if a schema applies itself to the same
+// value through $ref, never moving into the instance: validating it would
+// not end.
+func (c *compiler) checkCycles() error {
+	locs := make([]location, 0, len(c.tags))
+	tagLocs := make(map[*tag]location, len(c.tags))
+	for loc, t := range c.tags {
+		locs = append(locs, loc)
+		tagLocs[t] = loc
+	}
+	sort.Slice(locs, func(i, j int) bool {
+		if locs[i].doc != locs[j].doc {
+			return locs[i].doc < locs[j].doc
+		}
+		return locs[i].ptr < locs[j].ptr
+	})
+
+	const visiting, done = 1, 2
+	states := map[*tag]int{}
+	var visit func(t *tag) error
+	visit = func(t *tag) error {
+		switch states[t] {
+		case visiting:
+			loc := tagLocs[t]
+			return &SchemaError{URI: loc.doc, Pointer: loc.ptr, Reason: "$ref cycle never moves into the instance"}
+		case done:
+			return nil
+		}
+		states[t] = visiting
+		for _, sub := range t.inPlace() {
+			if err := visit(sub); err != nil {
+				return err
+			}
+		}
+		states[t] = done
+		return nil
+	}
+	for _, loc := range locs {
+		if err := visit(c.tags[loc]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inPlace returns the sub-tags of t applied to the same value as t.
+func (t *tag) inPlace() []*tag {
+	var subs []*tag
+	for _, sub := range []*tag{t.ref, t.not, t.ifTag, t.thenTag, t.elseTag} {
+		if sub != nil {
+			subs = append(subs, sub)
+		}
+	}
+	subs = append(subs, t.allOf...)
+	subs = append(subs, t.anyOf...)
+	subs = append(subs, t.oneOf...)
+	for _, d := range t.dependentSchemas {
+		subs = append(subs, d.tag)
+	}
+	return subs
+}
+
+// schemaKeywords lists the keywords compiled from documents, in the order
+// they are checked. Other keywords are ignored as annotations.
+var schemaKeywords = []string{
+	"$ref",
+	"type",
+	"minimum",
+	"maximum",
+	"exclusiveMinimum",
+	"exclusiveMaximum",
+	"multipleOf",
+	"minLength",
+	"maxLength",
+	"pattern",
+	"format",
+	"prefixItems",
+	"items",
+	"additionalItems",
+	"minItems",
+	"maxItems",
+	"uniqueItems",
+	"contains",
+	"minContains",
+	"maxContains",
+	"properties",
+	"minProperties",
+	"maxProperties",
+	"patternProperties",
+	"additionalProperties",
+	"propertyNames",
+	"required",
+	"dependentRequired",
+	"dependentSchemas",
+	"dependencies",
+	"enum",
+	"const",
+	"allOf",
+	"anyOf",
+	"oneOf",
+	"not",
+	"if",
+	"then",
+	"else",
+}
+
+func (c *compiler) compile(t *tag, node interface{}, loc location) error {
+	if b, ok := node.(bool); ok && c.draft != Draft04 {
+		// boolean schemas since draft 6
+		t.falseSchema = !b
+		return nil
+	}
+	obj, ok := node.(map[string]interface{})
+	if !ok {
+		return &SchemaError{URI: loc.doc, Pointer: loc.ptr, Reason: "schema must be an object"}
+	}
+
+	keywords := schemaKeywords
+	if _, ok := obj["$ref"]; ok && c.draft != 0 && c.draft <= Draft07 {
+		// keywords next to $ref are ignored before draft 2019-09
+		keywords = []string{"$ref"}
+	}
+	for _, keyword := range keywords {
+		value, ok := obj[keyword]
+		if !ok {
+			continue
+		}
+		if !c.draft.supports(keyword) {
+			return &SchemaError{URI: loc.doc, Pointer: loc.ptr, Keyword: keyword, Reason: fmt.Sprintf("keyword is not supported in %s", c.draft)}
+		}
+		if err := c.compileKeyword(t, keyword, value, loc); err != nil {
+			if e, ok := err.(*SchemaError); ok {
+				return e
+			}
+			return &SchemaError{URI: loc.doc, Pointer: loc.ptr, Keyword: keyword, Reason: err.Error()}
+		}
+	}
+	return nil
+}
+
+func (c *compiler) compileKeyword(t *tag, keyword string, value interface{}, loc location) error {
+	switch keyword {
+	case "$ref":
+		ref, ok := value.(string)
+		if !ok {
+			return errors.New("must be a string")
+		}
+		base := c.bases[loc]
+		if base == nil {
+			base = &url.URL{}
+		}
+		target, err := c.resolve(ref, base)
+		if err != nil {
+			return err
+		}
+		t.ref, err = c.compileAt(target)
+		return err
+	case "type":
+		types, err := stringList(value)
+		if str, ok := value.(string); ok {
+			types, err = []string{str}, nil
+		}
+		if err != nil {
+			return errors.New("must be a string or an array of strings")
+		}
+		for _, typ := range types {
+			if !contains(jsonTypes, typ) {
+				return fmt.Errorf("unknown type %q", typ)
+			}
+		}
+		t.types = types
+	case "minimum":
+		return setNumber(&t.minimum, value)
+	case "maximum":
+		return setNumber(&t.maximum, value)
+	case "exclusiveMinimum", "exclusiveMaximum":
+		if b, ok := value.(bool); ok {
+			if c.draft > Draft04 {
+				return fmt.Errorf("must be a number in %s", c.draft)
+			}
+			if keyword == "exclusiveMinimum" {
+				t.exclusiveMinimumD4 = &b
+			} else {
+				t.exclusiveMaximumD4 = &b
+			}
+			return nil
+		}
+		if c.draft == Draft04 {
+			return errors.New("must be a boolean in draft-04")
+		}
+		if keyword == "exclusiveMinimum" {
+			return setNumber(&t.exclusiveMinimumD6, value)
+		}
+		return setNumber(&t.exclusiveMaximumD6, value)
+	case "multipleOf":
+		if err := setNumber(&t.multipleOf, value); err != nil {
+			return err
+		}
+		if t.multipleOf.Sign() <= 0 {
+			return errors.New("must be greater than 0")
+		}
+	case "minLength":
+		return setInteger(&t.minLength, value)
+	case "maxLength":
+		return setInteger(&t.maxLength, value)
+	case "minItems":
+		return setInteger(&t.minItems, value)
+	case "maxItems":
+		return setInteger(&t.maxItems, value)
+	case "minContains":
+		return setInteger(&t.minContains, value)
+	case "maxContains":
+		return setInteger(&t.maxContains, value)
+	case "contains":
+		sub, err := c.compileAt(loc.child(keyword))
+		if err != nil {
+			return err
+		}
+		t.contains = sub
+	case "minProperties":
+		return setInteger(&t.minProperties, value)
+	case "maxProperties":
+		return setInteger(&t.maxProperties, value)
+	case "pattern":
+		str, ok := value.(string)
+		if !ok {
+			return errors.New("must be a string")
+		}
+		re, err := regexp.Compile(str)
+		if err != nil {
+			return fmt.Errorf("invalid regexp: %s", err)
+		}
+		t.pattern = re
+	case "format":
+		str, ok := value.(string)
+		if !ok {
+			return errors.New("must be a string")
+		}
+		t.format, t.stringFormat = &str, true
+	case "uniqueItems":
+		b, ok := value.(bool)
+		if !ok {
+			return errors.New("must be a boolean")
+		}
+		t.uniqueItems = &b
+	case "required":
+		required, err := stringList(value)
+		if err != nil {
+			return err
+		}
+		t.required = append(t.required, required...)
+	case "enum":
+		list, ok := value.([]interface{})
+		if !ok || len(list) == 0 {
+			return errors.New("must be a non-empty array")
+		}
+		for _, item := range list {
+			t.enum = append(t.enum, enumValue{value: item})
+		}
+	case "const":
+		t.constValue = &enumValue{value: value}
+	case "allOf", "anyOf", "oneOf":
+		list, ok := value.([]interface{})
+		if !ok || len(list) == 0 {
+			return errors.New("must be a non-empty array of schemas")
+		}
+		subs := make([]*tag, len(list))
+		for i := range list {
+			sub, err := c.compileAt(loc.child(keyword, strconv.Itoa(i)))
+			if err != nil {
+				return err
+			}
+			subs[i] = sub
+		}
+		switch keyword {
+		case "allOf":
+			t.allOf = subs
+		case "anyOf":
+			t.anyOf = subs
+		case "oneOf":
+			t.oneOf = subs
+		}
+	case "not":
+		sub, err := c.compileAt(loc.child(keyword))
+		if err != nil {
+			return err
+		}
+		t.not = sub
+	case "if", "then", "else":
+		sub, err := c.compileAt(loc.child(keyword))
+		if err != nil {
+			return err
+		}
+		switch keyword {
+		case "if":
+			t.ifTag = sub
+		case "then":
+			t.thenTag = sub
+		case "else":
+			t.elseTag = sub
+		}
+	case "prefixItems", "items":
+		list, ok := value.([]interface{})
+		if !ok {
+			if keyword == "prefixItems" {
+				return errors.New("must be a non-empty array of schemas")
+			}
+			items, err := c.compileAt(loc.child(keyword))
+			if err != nil {
+				return err
+			}
+			t.items = items
+			break
+		}
+		if keyword == "items" && c.draft >= Draft202012 {
+			return fmt.Errorf("must be a schema in %s, use prefixItems", c.draft)
+		}
+		if len(list) == 0 {
+			return errors.New("must be a non-empty array of schemas")
+		}
+		// an items array before draft 2020-12 is a prefixItems
+		t.prefixItems = make([]*tag, len(list))
+		for i := range list {
+			sub, err := c.compileAt(loc.child(keyword, strconv.Itoa(i)))
+			if err != nil {
+				return err
+			}
+			t.prefixItems[i] = sub
+		}
+	case "additionalItems":
+		if t.prefixItems == nil {
+			// ignored unless items is an array
+			break
+		}
+		items, err := c.compileAt(loc.child(keyword))
+		if err != nil {
+			return err
+		}
+		t.items = items
+	case "additionalProperties":
+		sub, err := c.compileAt(loc.child(keyword))
+		if err != nil {
+			return err
+		}
+		t.additionalProperties = sub
+	case "propertyNames":
+		sub, err := c.compileAt(loc.child(keyword))
+		if err != nil {
+			return err
+		}
+		t.keys = sub
+	case "patternProperties":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return errors.New("must be an object")
+		}
+		for _, source := range sortedKeys(obj) {
+			re, err := regexp.Compile(source)
+			if err != nil {
+				return fmt.Errorf("invalid regexp: %s", err)
+			}
+			sub, err := c.compileAt(loc.child(keyword, source))
+			if err != nil {
+				return err
+			}
+			t.patterns = append(t.patterns, patternProperty{re: re, tag: sub})
+		}
+	case "dependentRequired", "dependentSchemas", "dependencies":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return errors.New("must be an object")
+		}
+		for _, name := range sortedKeys(obj) {
+			_, isList := obj[name].([]interface{})
+			if keyword == "dependentSchemas" || (keyword == "dependencies" && !isList) {
+				sub, err := c.compileAt(loc.child(keyword, name))
+				if err != nil {
+					return err
+				}
+				t.dependentSchemas = append(t.dependentSchemas, dependency{name: name, tag: sub})
+				continue
+			}
+			required, err := stringList(obj[name])
+			if err != nil {
+				return err
+			}
+			t.dependentRequired = append(t.dependentRequired, dependency{name: name, required: required})
+		}
+	case "properties":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return errors.New("must be an object")
+		}
+		for _, name := range sortedKeys(obj) {
+			p, err := c.compileAt(loc.child("properties", name))
+			if err != nil {
+				return err
+			}
+			t.properties = append(t.properties, property{name: name, tag: p})
+		}
+	}
+	return nil
+}
+
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func stringList(value interface{}) ([]string, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, errors.New("must be an array of strings")
+	}
+	strs := make([]string, 0, len(list))
+	for _, item := range list {
+		str, ok := item.(string)
+		if !ok {
+			return nil, errors.New("must be an array of strings")
+		}
+		strs = append(strs, str)
+	}
+	return strs, nil
+}
+
+func setNumber(dst **big.Rat, value interface{}) error {
+	num, ok := value.(json.Number)
+	if !ok {
+		return errors.New("must be a number")
+	}
+	r, ok := parseNumber(num.String())
+	if !ok {
+		return errors.New("must be a number")
+	}
+	*dst = r
+	return nil
+}
+
+func setInteger(dst **int64, value interface{}) error {
+	num, ok := value.(json.Number)
+	if !ok {
+		return errors.New("must be a non-negative integer")
+	}
+	i, err := num.Int64()
+	if err != nil || i < 0 {
+		return errors.New("must be a non-negative integer")
+	}
+	*dst = &i
+	return nil
+}
@@ -0,0 +1,440 @@
+package jsonschema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSchema_Validate(t *testing.T) {
+	validator := NewValidator()
+
+	schema, err := validator.Compile([]byte(`{
+		"properties": {
+			"name": {"maxLength": 5, "pattern": "^[a-z]+$"},
+			"age": {"minimum": 0, "exclusiveMaximum": 150},
+			"email": {"format": "email"},
+			"tags": {
+				"minItems": 1,
+				"uniqueItems": true,
+				"items": {"enum": ["a", "b", "c"]}
+			}
+		},
+		"required": ["name"]
+	}`))
+	assert.NoError(t, err)
+
+	// valid
+	assert.NoError(t, schema.Validate([]byte(`{"name": "abc", "age": 20, "tags": ["a", "b"]}`)))
+	assert.NoError(t, schema.Validate(json.RawMessage(`{"name": "abc", "email": "abc@example.com"}`)))
+	assert.NoError(t, schema.Validate(map[string]interface{}{"name": "abc"}))
+
+	// invalid
+	err = schema.Validate([]byte(`{"age": 150}`))
+	assert.Error(t, err)
+	assert.Equal(t, "Missing required property: [name]; Value 150 is equal to exclusive maximum 150", err.Error())
+
+	err = schema.Validate([]byte(`{"name": "abcdef", "tags": ["a", "d", "a"]}`))
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, ret.Causes, 2)
+	assert.Equal(t, "name", ret.Causes[0].Causes[0].Name)
+	assert.Equal(t, "String is too long (6 chars), maximum 5", ret.Causes[0].Causes[0].Message)
+	assert.Equal(t, "tags", ret.Causes[1].Causes[0].Name)
+	assert.Equal(t, "Array items are not unique (indices 2 and 0)", ret.Causes[1].Causes[0].Message)
+	assert.Equal(t, "tags[1]", ret.Causes[1].Causes[1].Causes[0].Name)
+	assert.Equal(t, "No enum match for: d", ret.Causes[1].Causes[1].Causes[0].Message)
+
+	err = schema.Validate([]byte(`{"name": "abc", "email": "abc"}`))
+	assert.Error(t, err)
+	assert.Equal(t, "Format validation failed ()", err.Error())
+
+	// invalid json
+	assert.Error(t, schema.Validate([]byte(`{`)))
+
+	// numbers are decoded exactly
+	schema, err = validator.Compile([]byte(`{"maximum": 9007199254740992}`))
+	assert.NoError(t, err)
+	assert.NoError(t, schema.Validate([]byte(`9007199254740992`)))
+	assert.Equal(t, "Value 9007199254740993 is greater than maximum 9007199254740992", schema.Validate([]byte(`9007199254740993`)).Error())
+}
+
+func TestValidator_CompileReader(t *testing.T) {
+	validator := NewValidator()
+
+	schema, err := validator.CompileReader(strings.NewReader(`{"items": {"multipleOf": 2}}`))
+	assert.NoError(t, err)
+	assert.NoError(t, schema.Validate([]byte(`[2, 4, 6]`)))
+	assert.Equal(t, "Value 3 is not a multiple of 2", schema.Validate([]byte(`[2, 3]`)).Error())
+
+	_, err = validator.Compile([]byte(`{"properties": {"a/b": {"maxLength": -1}}}`))
+	assert.True(t, errors.Is(err, ErrSchema))
+	assert.Equal(t, `invalid schema at #/properties/a~1b: "maxLength": must be a non-negative integer`, err.Error())
+
+	_, err = validator.Compile([]byte(`{"pattern": "[a-z"}`))
+	assert.True(t, errors.Is(err, ErrSchema))
+
+	// the draft is taken from $schema
+	_, err = validator.Compile([]byte(`{"$schema": "http://json-schema.org/draft-04/schema#", "exclusiveMinimum": 5}`))
+	assert.Equal(t, `invalid schema at #: "exclusiveMinimum": must be a boolean in draft-04`, err.Error())
+	_, err = NewValidator(WithDraft(Draft07)).Compile([]byte(`{"minimum": 5, "exclusiveMinimum": true}`))
+	assert.Equal(t, `invalid schema at #: "exclusiveMinimum": must be a number in draft-07`, err.Error())
+}
+
+func TestSchema_Validate_Ref(t *testing.T) {
+	loader := MapLoader{
+		"https://example.com/schemas/user.json": []byte(`{
+			"$id": "https://example.com/schemas/user.json",
+			"properties": {
+				"name": {"$ref": "#/definitions/name"},
+				"address": {"$ref": "address.json"},
+				"friends": {"items": {"$ref": "#"}}
+			},
+			"definitions": {
+				"name": {"maxLength": 5}
+			}
+		}`),
+		"https://example.com/schemas/address.json": []byte(`{
+			"properties": {
+				"zip": {"$ref": "#/$defs/zip~1code"}
+			},
+			"$defs": {
+				"zip/code": {"pattern": "^[0-9]+$"}
+			}
+		}`),
+	}
+	validator := NewValidator(WithLoader(loader))
+
+	schema, err := validator.CompileURI("https://example.com/schemas/user.json")
+	assert.NoError(t, err)
+
+	assert.NoError(t, schema.Validate([]byte(`{"name": "abc", "address": {"zip": "123"}, "friends": [{"name": "def"}]}`)))
+	err = schema.Validate([]byte(`{"name": "abcdef"}`))
+	assert.Equal(t, "String is too long (6 chars), maximum 5", err.Error())
+	err = schema.Validate([]byte(`{"address": {"zip": "abc"}}`))
+	assert.Equal(t, "String does not match pattern: ^[0-9]+$", err.Error())
+	err = schema.Validate([]byte(`{"friends": [{"friends": [{"name": "abcdef"}]}]}`))
+	assert.Equal(t, "String is too long (6 chars), maximum 5", err.Error())
+
+	// a document compiled from bytes resolves relative to its $id
+	schema, err = validator.Compile([]byte(`{
+		"$id": "https://example.com/schemas/order.json",
+		"properties": {"customer": {"$ref": "user.json#/definitions/name"}}
+	}`))
+	assert.NoError(t, err)
+	assert.Error(t, schema.Validate([]byte(`{"customer": "abcdef"}`)))
+
+	_, err = validator.Compile([]byte(`{"$ref": "missing.json"}`))
+	assert.True(t, errors.Is(err, ErrSchema))
+
+	// documents without an absolute URI resolve relative to each other
+	validator = NewValidator(WithLoader(MapLoader{
+		"root.json":           []byte(`{"properties": {"pos": {"$ref": "defs.json#/$defs/pos"}}}`),
+		"defs.json":           []byte(`{"$defs": {"pos": {"$ref": "schemas/number.json"}}}`),
+		"schemas/number.json": []byte(`{"minimum": 0}`),
+	}))
+	schema, err = validator.CompileURI("root.json")
+	assert.NoError(t, err)
+	assert.Equal(t, "Value -1 is less than minimum 0", schema.Validate([]byte(`{"pos": -1}`)).Error())
+	schema, err = validator.Compile([]byte(`{"$ref": "defs.json#/$defs/pos"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "Value -1 is less than minimum 0", schema.Validate([]byte(`-1`)).Error())
+	_, err = NewValidator().Compile([]byte(`{"$ref": "#/definitions/missing"}`))
+	assert.Equal(t, `invalid schema at #/definitions/missing: json pointer token "definitions" not found`, err.Error())
+
+	// cycles of $ref which never move into the instance
+	_, err = validator.Compile([]byte(`{"$ref": "#"}`))
+	assert.Equal(t, `invalid schema at #: $ref cycle never moves into the instance`, err.Error())
+	_, err = validator.Compile([]byte(`{
+		"properties": {"a": {"$ref": "#/definitions/a"}},
+		"definitions": {
+			"a": {"allOf": [{"$ref": "#/definitions/b"}]},
+			"b": {"$ref": "#/definitions/a"}
+		}
+	}`))
+	assert.True(t, errors.Is(err, ErrSchema))
+	_, err = validator.Compile([]byte(`{"properties": {"next": {"$ref": "#"}}}`))
+	assert.NoError(t, err)
+}
+
+func TestSchema_Validate_RefAnchor(t *testing.T) {
+	validator := NewValidator()
+
+	schema, err := validator.Compile([]byte(`{
+		"$id": "https://example.com/root.json",
+		"properties": {
+			"a": {"$ref": "#short"},
+			"b": {"$ref": "item.json"}
+		},
+		"$defs": {
+			"short": {"$anchor": "short", "maxLength": 2},
+			"item": {"$id": "item.json", "minimum": 10}
+		}
+	}`))
+	assert.NoError(t, err)
+	assert.NoError(t, schema.Validate([]byte(`{"a": "ab", "b": 10}`)))
+	assert.Equal(t, "String is too long (3 chars), maximum 2", schema.Validate([]byte(`{"a": "abc"}`)).Error())
+	assert.Equal(t, "Value 9 is less than minimum 10", schema.Validate([]byte(`{"b": 9}`)).Error())
+}
+
+func TestFSLoader(t *testing.T) {
+	fsys := fstest.MapFS{
+		"schemas/root.json": &fstest.MapFile{Data: []byte(`{"items": {"$ref": "item.json"}}`)},
+		"schemas/item.json": &fstest.MapFile{Data: []byte(`{"maxLength": 1}`)},
+	}
+	validator := NewValidator(WithLoader(FSLoader(fsys)))
+
+	schema, err := validator.CompileURI("schemas/root.json")
+	assert.NoError(t, err)
+	assert.NoError(t, schema.Validate([]byte(`["a", "b"]`)))
+	assert.Error(t, schema.Validate([]byte(`["a", "bc"]`)))
+
+	_, err = validator.CompileURI("schemas/missing.json")
+	assert.True(t, errors.Is(err, ErrSchema))
+}
+
+func TestSchema_Validate_Combinators(t *testing.T) {
+	validator := NewValidator()
+
+	schema, err := validator.Compile([]byte(`{
+		"oneOf": [
+			{"properties": {"kind": {"enum": ["card"]}, "number": {"pattern": "^[0-9]{16}$"}}, "required": ["kind", "number"]},
+			{"properties": {"kind": {"enum": ["bank"]}, "iban": {"minLength": 15}}, "required": ["kind", "iban"]}
+		],
+		"not": {"required": ["debug"]}
+	}`))
+	assert.NoError(t, err)
+
+	assert.NoError(t, schema.Validate([]byte(`{"kind": "card", "number": "1234567812345678"}`)))
+	assert.NoError(t, schema.Validate([]byte(`{"kind": "bank", "iban": "DE89370400440532013000"}`)))
+
+	err = schema.Validate([]byte(`{"kind": "card", "number": "1234"}`))
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, "Does not match any schema of oneOf", ret.Causes[0].Message)
+	assert.Len(t, ret.Causes[0].Causes, 2)
+
+	err = schema.Validate([]byte(`{"kind": "bank", "iban": "DE89370400440532013000", "debug": true}`))
+	assert.Equal(t, "Matches the schema of not", err.Error())
+
+	_, err = validator.Compile([]byte(`{"anyOf": []}`))
+	assert.Equal(t, `invalid schema at #: "anyOf": must be a non-empty array of schemas`, err.Error())
+}
+
+func TestSchema_Validate_IfThenElse(t *testing.T) {
+	validator := NewValidator()
+
+	schema, err := validator.Compile([]byte(`{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"if": {"properties": {"country": {"enum": ["US"]}}, "required": ["country"]},
+		"then": {"properties": {"postalCode": {"pattern": "^[0-9]{5}$"}}},
+		"else": {"properties": {"postalCode": {"minLength": 3}}}
+	}`))
+	assert.NoError(t, err)
+
+	assert.NoError(t, schema.Validate([]byte(`{"country": "US", "postalCode": "94105"}`)))
+	assert.NoError(t, schema.Validate([]byte(`{"country": "JP", "postalCode": "100-0001"}`)))
+
+	err = schema.Validate([]byte(`{"country": "US", "postalCode": "SW1A"}`))
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, "Does not match the schema of then", ret.Causes[0].Message)
+	assert.Len(t, ret.Causes[0].Causes, 1)
+
+	err = schema.Validate([]byte(`{"postalCode": "1"}`))
+	assert.Equal(t, "Does not match the schema of else", err.(*ValidationError).Causes[0].Message)
+
+	_, err = validator.Compile([]byte(`{"$schema": "http://json-schema.org/draft-06/schema#", "if": {}}`))
+	assert.Equal(t, `invalid schema at #: "if": keyword is not supported in draft-06`, err.Error())
+}
+
+func TestSchema_Validate_Contains(t *testing.T) {
+	validator := NewValidator()
+
+	schema, err := validator.Compile([]byte(`{
+		"$schema": "https://json-schema.org/draft/2019-09/schema",
+		"properties": {
+			"roles": {"contains": {"enum": ["admin"]}, "maxContains": 1}
+		}
+	}`))
+	assert.NoError(t, err)
+
+	assert.NoError(t, schema.Validate([]byte(`{"roles": ["user", "admin"]}`)))
+
+	err = schema.Validate([]byte(`{"roles": ["user"]}`))
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	roles := ret.Causes[0].Causes[0]
+	assert.Equal(t, "Array contains too few matching items (0), minimum 1", roles.Message)
+	assert.Equal(t, "roles[0]", roles.Causes[0].Name)
+
+	err = schema.Validate([]byte(`{"roles": ["admin", "admin"]}`))
+	assert.Equal(t, "Array contains too many matching items (2), maximum 1", err.(*ValidationError).Causes[0].Causes[0].Message)
+
+	_, err = validator.Compile([]byte(`{"$schema": "http://json-schema.org/draft-07/schema#", "minContains": 1}`))
+	assert.Equal(t, `invalid schema at #: "minContains": keyword is not supported in draft-07`, err.Error())
+}
+
+func TestSchema_Validate_Tuple(t *testing.T) {
+	validator := NewValidator()
+
+	for _, doc := range []string{
+		`{"$schema": "http://json-schema.org/draft-07/schema#", "items": [{"type": "string", "maxLength": 3}, {"minimum": 0}], "additionalItems": false}`,
+		`{"$schema": "https://json-schema.org/draft/2020-12/schema", "prefixItems": [{"maxLength": 3}, {"minimum": 0}], "items": false}`,
+	} {
+		schema, err := validator.Compile([]byte(doc))
+		assert.NoError(t, err)
+
+		assert.NoError(t, schema.Validate([]byte(`["abc", 1]`)))
+		assert.NoError(t, schema.Validate([]byte(`["abc"]`)))
+
+		err = schema.Validate([]byte(`["abcd", -1, true]`))
+		ret, ok := err.(*ValidationError)
+		assert.True(t, ok)
+		assert.Len(t, ret.Causes, 3)
+		assert.Equal(t, "[1]", ret.Causes[1].Causes[0].Name)
+		assert.Equal(t, "Value -1 is less than minimum 0", ret.Causes[1].Causes[0].Message)
+		assert.Equal(t, "[2]", ret.Causes[2].Name)
+		assert.Equal(t, "Additional item 2 is not allowed", ret.Causes[2].Message)
+	}
+
+	schema, err := validator.Compile([]byte(`{"properties": {"legacy": false}}`))
+	assert.NoError(t, err)
+	err = schema.Validate([]byte(`{"legacy": 1}`))
+	assert.Equal(t, "Value is not allowed", err.Error())
+
+	_, err = validator.Compile([]byte(`{"$schema": "https://json-schema.org/draft/2020-12/schema", "items": [{}]}`))
+	assert.Equal(t, `invalid schema at #: "items": must be a schema in draft 2020-12, use prefixItems`, err.Error())
+}
+
+func TestSchema_Validate_AdditionalProperties(t *testing.T) {
+	validator := NewValidator()
+
+	schema, err := validator.Compile([]byte(`{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"properties": {"name": {"maxLength": 5}},
+		"patternProperties": {"^x-": {"minLength": 1}},
+		"additionalProperties": {"maximum": 10},
+		"propertyNames": {"maxLength": 12},
+		"dependencies": {
+			"creditCard": ["billingAddress"],
+			"name": {"required": ["x-id"]}
+		}
+	}`))
+	assert.NoError(t, err)
+
+	assert.NoError(t, schema.Validate([]byte(`{"name": "test", "x-id": "1", "count": 3}`)))
+
+	err = schema.Validate([]byte(`{"creditCard": 1, "count": 11, "verificationCode": 1}`))
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, ret.Causes, 3)
+	assert.Equal(t, "Missing property billingAddress required by creditCard", ret.Causes[0].Message)
+	assert.Equal(t, "count", ret.Causes[1].Causes[0].Name)
+	assert.Equal(t, "Value 11 is greater than maximum 10", ret.Causes[1].Causes[0].Message)
+	assert.Equal(t, "[verificationCode](key)", ret.Causes[2].Causes[0].Name)
+
+	err = schema.Validate([]byte(`{"name": "test"}`))
+	assert.Equal(t, "Missing required property: [x-id]", err.Error())
+
+	_, err = validator.Compile([]byte(`{"$schema": "https://json-schema.org/draft/2020-12/schema", "dependencies": {}}`))
+	assert.Equal(t, `invalid schema at #: "dependencies": keyword is not supported in draft 2020-12`, err.Error())
+}
+
+func TestSchema_Validate_Type(t *testing.T) {
+	validator := NewValidator()
+
+	schema, err := validator.Compile([]byte(`{
+		"type": "object",
+		"properties": {
+			"count": {"type": "integer"},
+			"metadata": {"type": ["object", "null"]}
+		}
+	}`))
+	assert.NoError(t, err)
+
+	assert.NoError(t, schema.Validate([]byte(`{"count": 1.0, "metadata": null}`)))
+
+	err = schema.Validate([]byte(`{"count": 1.5, "metadata": []}`))
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, ret.Causes, 2)
+	assert.Equal(t, "Invalid type, expected integer but got number", ret.Causes[0].Causes[0].Message)
+	assert.Equal(t, "Invalid type, expected object or null but got array", ret.Causes[1].Causes[0].Message)
+
+	err = schema.Validate([]byte(`"text"`))
+	assert.Equal(t, "Invalid type, expected object but got string", err.Error())
+
+	_, err = validator.Compile([]byte(`{"type": "text"}`))
+	assert.Equal(t, `invalid schema at #: "type": unknown type "text"`, err.Error())
+}
+
+func TestSchema_Validate_EnumConst(t *testing.T) {
+	validator := NewValidator()
+
+	schema, err := validator.Compile([]byte(`{
+		"properties": {
+			"version": {"const": 2},
+			"mode": {"enum": ["auto", 1, true, null, {"level": 1}]}
+		}
+	}`))
+	assert.NoError(t, err)
+
+	assert.NoError(t, schema.Validate([]byte(`{"version": 2.0, "mode": {"level": 1.0}}`)))
+	assert.NoError(t, schema.Validate([]byte(`{"version": 2, "mode": null}`)))
+
+	err = schema.Validate([]byte(`{"version": "2", "mode": "1"}`))
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, ret.Causes, 2)
+	assert.Equal(t, "No enum match for: 1", ret.Causes[0].Causes[0].Message)
+	assert.Equal(t, "Value 2 does not match const 2", ret.Causes[1].Causes[0].Message)
+}
+
+func TestSchema_Validate_Format(t *testing.T) {
+	validator := NewValidator()
+
+	schema, err := validator.Compile([]byte(`{
+		"properties": {
+			"id": {"format": "uuid"},
+			"email": {"format": "email"}
+		}
+	}`))
+	assert.NoError(t, err)
+
+	// unknown formats are ignored, and formats only check strings
+	assert.NoError(t, schema.Validate([]byte(`{"id": "abc", "email": 5}`)))
+	assert.NoError(t, schema.Validate([]byte(`{"email": "user@example.com"}`)))
+	assert.Error(t, schema.Validate([]byte(`{"email": "user"}`)))
+}
+
+func TestSchema_Validate_Path(t *testing.T) {
+	schema, err := NewValidator().Compile([]byte(`{
+		"$defs": {"name": {"maxLength": 3}},
+		"properties": {
+			"users": {"items": {"properties": {"name": {"$ref": "#/$defs/name"}}}}
+		}
+	}`))
+	assert.NoError(t, err)
+
+	err = schema.Validate([]byte(`{"users": [{"name": "abc"}, {"name": "abcd"}]}`))
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	name := ret.Causes[0].Causes[0].Causes[0].Causes[0].Causes[0]
+	assert.Equal(t, "/users/1/name", name.InstancePath)
+	assert.Equal(t, "/properties/users/items/properties/name/$ref/maxLength", name.KeywordLocation)
+	assert.Equal(t, "maxLength", name.Keyword)
+}
+
+func TestSchema_ValidateContext_Locale(t *testing.T) {
+	schema, err := NewValidator().Compile([]byte(`{"properties": {"name": {"maxLength": 3}}}`))
+	assert.NoError(t, err)
+
+	ctx := ContextWithLocale(context.Background(), "ja")
+	err = schema.ValidateContext(ctx, []byte(`{"name": "abcd"}`))
+	assert.Equal(t, "文字列が長すぎます (4文字)。最大3文字です", err.Error())
+}
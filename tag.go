@@ -3,10 +3,12 @@ package jsonschema
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"math/big"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
-	"strings"
 )
 
 var (
@@ -14,350 +16,757 @@ var (
 	ErrTagSyntax = errors.New("tag syntax error")
 )
 
-var (
-	preMinimum        = []byte("mini")
-	preMaximum        = []byte("maxi")
-	mum               = []byte("mum")
-	preExclusive      = []byte("excl")
-	exclusiveMaximum  = []byte("usiveMaximum")
-	exclusiveMinimum  = []byte("usiveMinimum")
-	preMultipleOf     = []byte("mult")
-	multipleOf        = []byte("ipleOf")
-	preMinLength      = []byte("minL")
-	preMaxLength      = []byte("maxL")
-	length            = []byte("ength")
-	prePattern        = []byte("patt")
-	pattern           = []byte("ern")
-	patternProperties = []byte("roperties:")
-	preFormat         = []byte("form")
-	format            = []byte("at")
-	preMinItems       = []byte("minI")
-	preMaxItems       = []byte("maxI")
-	items             = []byte("tems")
-	preUniqueItems    = []byte("uniq")
-	uniqueItems       = []byte("ueItems")
-	preMinProperties  = []byte("minP")
-	preMaxProperties  = []byte("maxP")
-	properties        = []byte("roperties")
-	preRequired       = []byte("requ")
-	required          = []byte("ired")
-	preEnum           = []byte("enum")
+// TagError -
+type TagError struct {
+	Type    string // struct type
+	Field   string
+	Keyword string
+	Offset  int // byte offset in the tag value
+	Reason  string
+}
+
+// Error -
+func (e *TagError) Error() string {
+	buf := bytes.NewBufferString(ErrTagSyntax.Error())
+	if e.Type != "" || e.Field != "" {
+		fmt.Fprintf(buf, " in %s.%s", e.Type, e.Field)
+	}
+	if e.Keyword != "" {
+		fmt.Fprintf(buf, ": %q", e.Keyword)
+	}
+	fmt.Fprintf(buf, " at offset %d: %s", e.Offset, e.Reason)
+	return buf.String()
+}
+
+// Unwrap -
+func (e *TagError) Unwrap() error {
+	return ErrTagSyntax
+}
+
+// kinds is a set of value kinds a keyword applies to.
+type kinds uint8
+
+const (
+	numberKinds kinds = 1 << iota
+	stringKinds
+	arrayKinds
+	objectKinds
+	structKinds
+	booleanKinds
+	anyKinds = numberKinds | stringKinds | arrayKinds | objectKinds | structKinds | booleanKinds
 )
 
+// typeKinds returns the kinds of keywords that apply to values of type rt.
+func typeKinds(rt reflect.Type) kinds {
+	if isNumberType(rt) {
+		return numberKinds
+	}
+	return kindsOf(rt.Kind())
+}
+
+func kindsOf(k reflect.Kind) kinds {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return numberKinds
+	case reflect.String:
+		return stringKinds
+	case reflect.Slice, reflect.Array:
+		return arrayKinds
+	case reflect.Map:
+		return objectKinds
+	case reflect.Struct:
+		return structKinds
+	case reflect.Bool:
+		return booleanKinds
+	case reflect.Interface:
+		return anyKinds
+	}
+	return 0
+}
+
+var tagKeywords = map[string]kinds{
+	"minimum":              numberKinds,
+	"maximum":              numberKinds,
+	"exclusiveMinimum":     numberKinds,
+	"exclusiveMaximum":     numberKinds,
+	"multipleOf":           numberKinds,
+	"minLength":            stringKinds,
+	"maxLength":            stringKinds,
+	"pattern":              stringKinds,
+	"type":                 anyKinds,
+	"format":               anyKinds,
+	"minItems":             arrayKinds,
+	"maxItems":             arrayKinds,
+	"prefixItems":          arrayKinds,
+	"items":                arrayKinds,
+	"uniqueItems":          arrayKinds,
+	"contains":             arrayKinds,
+	"minContains":          arrayKinds,
+	"maxContains":          arrayKinds,
+	"properties":           objectKinds | structKinds,
+	"keys":                 objectKinds,
+	"values":               objectKinds,
+	"minProperties":        objectKinds,
+	"maxProperties":        objectKinds,
+	"patternProperties":    objectKinds,
+	"additionalProperties": objectKinds,
+	"propertyNames":        objectKinds,
+	"dependentRequired":    objectKinds,
+	"required":             objectKinds | structKinds,
+	"nullable":             anyKinds,
+	"enum":                 anyKinds,
+	"const":                anyKinds,
+	"allOf":                anyKinds,
+	"anyOf":                anyKinds,
+	"oneOf":                anyKinds,
+	"not":                  anyKinds,
+	"if":                   anyKinds,
+	"then":                 anyKinds,
+	"else":                 anyKinds,
+}
+
+type tagKeyword struct {
+	name   string
+	offset int
+}
+
 func newTag() *tag {
 	return &tag{
 		required: []string{},
-		enum:     []string{},
 	}
 }
 
 type tag struct {
+	// falseSchema is set for the boolean schema false, which no value matches
+	falseSchema bool
 	// number validators
-	minimum            *big.Float
-	maximum            *big.Float
-	exclusiveMinimumD4 *bool      // draft 4
-	exclusiveMaximumD4 *bool      // draft 4
-	exclusiveMinimumD6 *big.Float // draft 6
-	exclusiveMaximumD6 *big.Float // draft 6
-	multipleOf         *big.Float
+	minimum            *big.Rat
+	maximum            *big.Rat
+	exclusiveMinimumD4 *bool    // draft 4
+	exclusiveMaximumD4 *bool    // draft 4
+	exclusiveMinimumD6 *big.Rat // draft 6
+	exclusiveMaximumD6 *big.Rat // draft 6
+	multipleOf         *big.Rat
+	// type validator
+	types []string
 	// string validators
 	minLength *int64
 	maxLength *int64
 	pattern   *regexp.Regexp
 	format    *string
+	// stringFormat makes format ignore other kinds of values and unknown
+	// formats, as in JSON Schema documents
+	stringFormat bool
 	// array validations
+	prefixItems []*tag
+	items       *tag
 	minItems    *int64
 	maxItems    *int64
 	uniqueItems *bool
+	contains    *tag
+	minContains *int64
+	maxContains *int64
 	// object validations
-	minProperties     *int64
-	maxProperties     *int64
-	patternProperties *regexp.Regexp
-	required          []string
+	properties           []property // sorted by name
+	minProperties        *int64
+	maxProperties        *int64
+	patternProperties    *regexp.Regexp // legacy: every key has to match
+	patterns             []patternProperty
+	additionalProperties *tag
+	required             []string
+	fieldRequired        bool // bare required on a struct field
+	nullable             bool
+	dependentRequired    []dependency // sorted by name
+	dependentSchemas     []dependency // sorted by name
+	keys                 *tag         // propertyNames
+	values               *tag
 	// all validations
-	enum []string
+	enum       []enumValue
+	constValue *enumValue
+	ref        *tag
+	allOf      []*tag
+	anyOf      []*tag
+	oneOf      []*tag
+	not        *tag
+	// conditional validations
+	ifTag   *tag
+	thenTag *tag
+	elseTag *tag
+
+	keywords []tagKeyword // in tag order
+
+	// inherited is applied to the elements of arrays when no items are
+	// given, as struct tags always did. It is the tag itself without format.
+	inherited *tag
+}
+
+type property struct {
+	name string
+	tag  *tag
+}
+
+type patternProperty struct {
+	re  *regexp.Regexp
+	tag *tag
+}
+
+// dependency is a property of dependentRequired or dependentSchemas, applied
+// when the object has the property.
+type dependency struct {
+	name     string
+	required []string
+	tag      *tag
 }
 
-func (t *tag) read(r *reader) error {
-	if r.IsEOF() {
+// itemTag returns the tag applied to the i-th element of an array: the
+// prefixItems tag at i, or the items tag. Without an items tag, the elements
+// inherit the keywords of the array tag.
+func (t *tag) itemTag(i int) *tag {
+	if t == nil {
 		return nil
 	}
-	prefix := r.ReadBytes(4)
-	switch {
-	case bytes.Equal(prefix[:], preMinimum):
-		prefix = r.ReadBytes(3)
-		if !bytes.Equal(prefix[:], mum) {
-			return ErrTagSyntax
-		}
-		r.SkipDelimiter()
-		value := r.ReadSeparator()
-		min, err := strconv.ParseFloat(value, 64)
+	if i < len(t.prefixItems) {
+		return t.prefixItems[i]
+	}
+	if t.items != nil || t.inherited == nil {
+		return t.items
+	}
+	return t.inherited
+}
+
+// keysTag returns the tag applied to each key of a map.
+func (t *tag) keysTag() *tag {
+	if t == nil {
+		return nil
+	}
+	return t.keys
+}
+
+// valuesTag returns the tag applied to each value of a map.
+func (t *tag) valuesTag() *tag {
+	if t == nil {
+		return nil
+	}
+	return t.values
+}
+
+// inheritedBy reports whether the keywords of the tag are inherited by the
+// elements of value instead of applying to value itself.
+func (t *tag) inheritedBy(value reflect.Value) bool {
+	return t.inherited != nil && t.items == nil && len(t.prefixItems) == 0 &&
+		(value.Kind() == reflect.Slice || value.Kind() == reflect.Array)
+}
+
+// inherit sets the tag applied to array elements: the keywords checking a
+// particular kind of value.
+func (t *tag) inherit() {
+	inherited := &tag{
+		minimum:              t.minimum,
+		maximum:              t.maximum,
+		exclusiveMinimumD4:   t.exclusiveMinimumD4,
+		exclusiveMaximumD4:   t.exclusiveMaximumD4,
+		exclusiveMinimumD6:   t.exclusiveMinimumD6,
+		exclusiveMaximumD6:   t.exclusiveMaximumD6,
+		multipleOf:           t.multipleOf,
+		minLength:            t.minLength,
+		maxLength:            t.maxLength,
+		pattern:              t.pattern,
+		minItems:             t.minItems,
+		maxItems:             t.maxItems,
+		uniqueItems:          t.uniqueItems,
+		contains:             t.contains,
+		minContains:          t.minContains,
+		maxContains:          t.maxContains,
+		properties:           t.properties,
+		minProperties:        t.minProperties,
+		maxProperties:        t.maxProperties,
+		patternProperties:    t.patternProperties,
+		patterns:             t.patterns,
+		additionalProperties: t.additionalProperties,
+		required:             t.required,
+		dependentRequired:    t.dependentRequired,
+		keys:                 t.keys,
+		values:               t.values,
+		enum:                 t.enum,
+		constValue:           t.constValue,
+	}
+	inherited.inherited = inherited
+	t.inherited = inherited
+}
+
+// readSubTag reads a parenthesized tag such as "(maxLength:5)".
+func readSubTag(r *reader) (*tag, error) {
+	group, err := r.ReadGroup()
+	if err != nil {
+		return nil, err
+	}
+	sub := newTag()
+	if err := sub.read(group); err != nil {
+		return nil, err
+	}
+	sub.inherit()
+	return sub, nil
+}
+
+// readBoolOrSubTag reads either a parenthesized tag or a boolean after a
+// delimiter, which stands for a tag matching any value or none.
+func readBoolOrSubTag(r *reader) (*tag, error) {
+	if b, ok := r.PeekByte(); ok && b == '(' {
+		return readSubTag(r)
+	}
+	if err := r.SkipDelimiter(); err != nil {
+		return nil, errors.New("expected '(' or ':' after keyword")
+	}
+	offset := r.pos
+	value, _, err := r.ReadString()
+	if err != nil {
+		return nil, &TagError{Offset: offset, Reason: err.Error()}
+	}
+	allow, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, &TagError{Offset: offset, Reason: fmt.Sprintf("invalid boolean %q", value)}
+	}
+	sub := newTag()
+	sub.falseSchema = !allow
+	sub.inherit()
+	return sub, nil
+}
+
+// readSubTags reads a parenthesized list of tags such as
+// "((maxLength:5),(format:email))".
+func readSubTags(r *reader) ([]*tag, error) {
+	group, err := r.ReadGroup()
+	if err != nil {
+		return nil, err
+	}
+	subs := []*tag{}
+	for {
+		group.SkipSpaces()
+		sub, err := readSubTag(group)
 		if err != nil {
-			return ErrTagSyntax
+			return nil, err
 		}
-		t.minimum = big.NewFloat(min)
-		if !r.IsEOF() {
-			t.read(r)
+		subs = append(subs, sub)
+		group.SkipSpaces()
+		if group.IsEOF() {
+			return subs, nil
 		}
-	case bytes.Equal(prefix[:], preMaximum):
-		prefix = r.ReadBytes(3)
-		if !bytes.Equal(prefix[:], mum) {
-			return ErrTagSyntax
+		if b, _ := group.ReadByte(); b != ',' {
+			return nil, &TagError{Offset: group.pos - 1, Reason: fmt.Sprintf("unexpected %q, expected ','", b)}
 		}
-		r.SkipDelimiter()
-		value := r.ReadSeparator()
-		max, err := strconv.ParseFloat(value, 64)
+	}
+}
+
+// readProperties reads a parenthesized list of named tags such as
+// "(name(maxLength:5),age(minimum:0))".
+func readProperties(r *reader) ([]property, error) {
+	group, err := r.ReadGroup()
+	if err != nil {
+		return nil, err
+	}
+	properties := []property{}
+	for {
+		group.SkipSpaces()
+		offset := group.pos
+		name := group.ReadName()
+		if name == "" {
+			return nil, &TagError{Offset: offset, Reason: "expected property name"}
+		}
+		sub, err := readSubTag(group)
 		if err != nil {
-			return ErrTagSyntax
-		}
-		t.maximum = big.NewFloat(max)
-		if !r.IsEOF() {
-			t.read(r)
-		}
-	case bytes.Equal(prefix[:], preExclusive):
-		prefix = r.ReadBytes(12)
-		r.SkipDelimiter()
-		value := r.ReadSeparator()
-		if bytes.Equal(prefix[:], exclusiveMinimum) {
-			if value == "true" || value == "false" {
-				exclusive, _ := strconv.ParseBool(value)
-				t.exclusiveMinimumD4 = &exclusive
-			} else {
-				exclusive, err := strconv.ParseFloat(value, 64)
-				if err != nil {
-					return ErrTagSyntax
-				}
-				t.exclusiveMinimumD6 = big.NewFloat(exclusive)
-			}
+			return nil, err
 		}
-		if bytes.Equal(prefix[:], exclusiveMaximum) {
-			if value == "true" || value == "false" {
-				exclusive, _ := strconv.ParseBool(value)
-				t.exclusiveMaximumD4 = &exclusive
-			} else {
-				exclusive, err := strconv.ParseFloat(value, 64)
-				if err != nil {
-					return ErrTagSyntax
-				}
-				t.exclusiveMaximumD6 = big.NewFloat(exclusive)
-			}
-		}
-		if !r.IsEOF() {
-			t.read(r)
+		properties = append(properties, property{name: name, tag: sub})
+		group.SkipSpaces()
+		if group.IsEOF() {
+			break
 		}
-	case bytes.Equal(prefix[:], preMultipleOf):
-		prefix = r.ReadBytes(6)
-		if !bytes.Equal(prefix[:], multipleOf) {
-			return ErrTagSyntax
+		if b, _ := group.ReadByte(); b != ',' {
+			return nil, &TagError{Offset: group.pos - 1, Reason: fmt.Sprintf("unexpected %q, expected ','", b)}
 		}
-		r.SkipDelimiter()
-		value := r.ReadSeparator()
-		num, err := strconv.ParseFloat(value, 64)
+	}
+	sort.Slice(properties, func(i, j int) bool {
+		return properties[i].name < properties[j].name
+	})
+	return properties, nil
+}
+
+// readPatternProperties reads a parenthesized list of regexps and tags such
+// as "((^x-)(maxLength:5),(^y-)(minimum:0))".
+func readPatternProperties(r *reader) ([]patternProperty, error) {
+	group, err := r.ReadGroup()
+	if err != nil {
+		return nil, err
+	}
+	patterns := []patternProperty{}
+	for {
+		group.SkipSpaces()
+		offset := group.pos
+		source, err := group.ReadGroup()
 		if err != nil {
-			return ErrTagSyntax
+			return nil, &TagError{Offset: offset, Reason: err.Error()}
 		}
-		t.multipleOf = big.NewFloat(num)
-		if !r.IsEOF() {
-			t.read(r)
-		}
-	case bytes.Equal(prefix[:], preMinLength):
-		prefix = r.ReadBytes(5)
-		if !bytes.Equal(prefix[:], length) {
-			return ErrTagSyntax
+		re, err := regexp.Compile(string(source.buf[source.pos:source.max]))
+		if err != nil {
+			return nil, &TagError{Offset: offset, Reason: fmt.Sprintf("invalid regexp: %s", err)}
 		}
-		r.SkipDelimiter()
-		value := r.ReadSeparator()
-		num, err := strconv.ParseInt(value, 10, 64)
+		sub, err := readSubTag(group)
 		if err != nil {
-			return ErrTagSyntax
+			return nil, err
 		}
-		t.minLength = &num
-		if !r.IsEOF() {
-			t.read(r)
+		patterns = append(patterns, patternProperty{re: re, tag: sub})
+		group.SkipSpaces()
+		if group.IsEOF() {
+			return patterns, nil
 		}
-	case bytes.Equal(prefix[:], preMaxLength):
-		prefix = r.ReadBytes(5)
-		if !bytes.Equal(prefix[:], length) {
-			return ErrTagSyntax
+		if b, _ := group.ReadByte(); b != ',' {
+			return nil, &TagError{Offset: group.pos - 1, Reason: fmt.Sprintf("unexpected %q, expected ','", b)}
 		}
-		r.SkipDelimiter()
-		value := r.ReadSeparator()
-		num, err := strconv.ParseInt(value, 10, 64)
+	}
+}
+
+// readDependencies reads a parenthesized list of names and lists such as
+// "(creditCard:[billingAddress,cvv])".
+func readDependencies(r *reader) ([]dependency, error) {
+	group, err := r.ReadGroup()
+	if err != nil {
+		return nil, err
+	}
+	dependencies := []dependency{}
+	for {
+		group.SkipSpaces()
+		offset := group.pos
+		name := group.ReadName()
+		if name == "" {
+			return nil, &TagError{Offset: offset, Reason: "expected property name"}
+		}
+		if err := group.SkipDelimiter(); err != nil {
+			return nil, &TagError{Offset: group.pos, Reason: err.Error()}
+		}
+		group.SkipSpaces()
+		list, err := group.ReadList()
 		if err != nil {
-			return ErrTagSyntax
+			return nil, &TagError{Offset: group.pos, Reason: err.Error()}
 		}
-		t.maxLength = &num
-		if !r.IsEOF() {
-			t.read(r)
+		for i := range list {
+			list[i], _ = unquote(list[i])
 		}
-	case bytes.Equal(prefix[:], prePattern):
-		prefix = r.ReadBytes(3)
-		if !bytes.Equal(prefix[:], pattern) {
-			return ErrTagSyntax
+		dependencies = append(dependencies, dependency{name: name, required: list})
+		group.SkipSpaces()
+		if group.IsEOF() {
+			break
 		}
-		hasSep, err := r.ReadByte()
-		if err != nil {
-			return ErrTagSyntax
+		if b, _ := group.ReadByte(); b != ',' {
+			return nil, &TagError{Offset: group.pos - 1, Reason: fmt.Sprintf("unexpected %q, expected ','", b)}
 		}
-		if hasSep == byte(':') || hasSep == byte('=') {
-			t.pattern = regexp.MustCompile(r.ReadSeparator())
-		} else {
-			prefix = r.ReadBytes(10)
-			if bytes.Equal(prefix[:], patternProperties) {
-				t.patternProperties = regexp.MustCompile(r.ReadSeparator())
+	}
+	sort.Slice(dependencies, func(i, j int) bool {
+		return dependencies[i].name < dependencies[j].name
+	})
+	return dependencies, nil
+}
+
+func (t *tag) property(name string) *tag {
+	i := sort.Search(len(t.properties), func(i int) bool {
+		return t.properties[i].name >= name
+	})
+	if i < len(t.properties) && t.properties[i].name == name {
+		return t.properties[i].tag
+	}
+	return nil
+}
+
+func (t *tag) read(r *reader) *TagError {
+	for {
+		r.SkipSpaces()
+		if r.IsEOF() {
+			return nil
+		}
+		offset := r.pos
+		keyword := r.ReadKeyword()
+		if keyword == "" {
+			return &TagError{Offset: offset, Reason: "expected keyword"}
+		}
+		if _, ok := tagKeywords[keyword]; !ok {
+			return &TagError{Keyword: keyword, Offset: offset, Reason: "unknown keyword"}
+		}
+		t.keywords = append(t.keywords, tagKeyword{name: keyword, offset: offset})
+		if err := t.readKeyword(r, keyword); err != nil {
+			if e, ok := err.(*TagError); ok {
+				if e.Keyword == "" {
+					e.Keyword = keyword
+				}
+				return e
 			}
+			return &TagError{Keyword: keyword, Offset: offset, Reason: err.Error()}
 		}
-		if !r.IsEOF() {
-			t.read(r)
+		r.SkipSpaces()
+		if r.IsEOF() {
+			return nil
 		}
-	case bytes.Equal(prefix[:], preFormat):
-		prefix = r.ReadBytes(2)
-		if !bytes.Equal(prefix[:], format) {
-			return ErrTagSyntax
+		if b, _ := r.ReadByte(); b != ',' {
+			return &TagError{Keyword: keyword, Offset: r.pos - 1, Reason: fmt.Sprintf("unexpected %q, expected ','", b)}
 		}
-		r.SkipDelimiter()
-		value := r.ReadSeparator()
-		t.format = &value
-		if !r.IsEOF() {
-			t.read(r)
+	}
+}
+
+func (t *tag) readKeyword(r *reader, keyword string) error {
+	var err error
+	switch keyword {
+	case "allOf":
+		t.allOf, err = readSubTags(r)
+		return err
+	case "anyOf":
+		t.anyOf, err = readSubTags(r)
+		return err
+	case "oneOf":
+		t.oneOf, err = readSubTags(r)
+		return err
+	case "not":
+		t.not, err = readSubTag(r)
+		return err
+	case "if":
+		t.ifTag, err = readSubTag(r)
+		return err
+	case "then":
+		t.thenTag, err = readSubTag(r)
+		return err
+	case "else":
+		t.elseTag, err = readSubTag(r)
+		return err
+	case "properties":
+		t.properties, err = readProperties(r)
+		return err
+	case "contains":
+		t.contains, err = readSubTag(r)
+		return err
+	case "prefixItems":
+		t.prefixItems, err = readSubTags(r)
+		return err
+	case "items":
+		t.items, err = readBoolOrSubTag(r)
+		return err
+	case "keys", "propertyNames":
+		t.keys, err = readSubTag(r)
+		return err
+	case "additionalProperties":
+		t.additionalProperties, err = readBoolOrSubTag(r)
+		return err
+	case "dependentRequired":
+		t.dependentRequired, err = readDependencies(r)
+		return err
+	case "patternProperties":
+		if b, ok := r.PeekByte(); ok && b == '(' {
+			t.patterns, err = readPatternProperties(r)
+			return err
+		}
+	case "values":
+		t.values, err = readSubTag(r)
+		return err
+	case "required", "nullable":
+		// bare keywords of struct fields
+		if b, ok := r.PeekByte(); !ok || b == ',' || b == ' ' {
+			if keyword == "required" {
+				t.fieldRequired = true
+			} else {
+				t.nullable = true
+			}
+			return nil
 		}
-	case bytes.Equal(prefix[:], preMinItems):
-		prefix = r.ReadBytes(4)
-		if !bytes.Equal(prefix[:], items) {
-			return ErrTagSyntax
+		if keyword == "nullable" {
+			return errors.New("unexpected value, nullable takes none")
 		}
-		r.SkipDelimiter()
-		value := r.ReadSeparator()
-		num, err := strconv.ParseInt(value, 10, 64)
-		if err != nil {
-			return ErrTagSyntax
+	}
+
+	if err := r.SkipDelimiter(); err != nil {
+		return err
+	}
+	offset := r.pos
+	valueError := func(reason string) error {
+		return &TagError{Keyword: keyword, Offset: offset, Reason: reason}
+	}
+
+	// lists are read by their keywords, other values are read here
+	var raw, value string
+	if b, _ := r.PeekByte(); keyword != "required" && keyword != "enum" && (keyword != "type" || b != '[') {
+		if raw, err = r.ReadValue(); err != nil {
+			return valueError(err.Error())
+		}
+		value, _ = unquote(raw)
+	}
+
+	switch keyword {
+	case "minimum", "maximum", "multipleOf":
+		num, ok := parseNumber(value)
+		if !ok {
+			return valueError(fmt.Sprintf("invalid number %q", value))
+		}
+		switch keyword {
+		case "minimum":
+			t.minimum = num
+		case "maximum":
+			t.maximum = num
+		case "multipleOf":
+			if num.Sign() <= 0 {
+				return valueError("multipleOf must be greater than 0")
+			}
+			t.multipleOf = num
+		}
+	case "exclusiveMinimum", "exclusiveMaximum":
+		if value == "true" || value == "false" {
+			exclusive, _ := strconv.ParseBool(value)
+			if keyword == "exclusiveMinimum" {
+				t.exclusiveMinimumD4 = &exclusive
+			} else {
+				t.exclusiveMaximumD4 = &exclusive
+			}
+			break
 		}
-		t.minItems = &num
-		if !r.IsEOF() {
-			t.read(r)
+		num, ok := parseNumber(value)
+		if !ok {
+			return valueError(fmt.Sprintf("invalid number or boolean %q", value))
 		}
-	case bytes.Equal(prefix[:], preMaxItems):
-		prefix = r.ReadBytes(4)
-		if !bytes.Equal(prefix[:], items) {
-			return ErrTagSyntax
+		if keyword == "exclusiveMinimum" {
+			t.exclusiveMinimumD6 = num
+		} else {
+			t.exclusiveMaximumD6 = num
 		}
-		r.SkipDelimiter()
-		value := r.ReadSeparator()
+	case "minLength", "maxLength", "minItems", "maxItems", "minContains", "maxContains", "minProperties", "maxProperties":
 		num, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || num < 0 {
+			return valueError(fmt.Sprintf("invalid non-negative integer %q", value))
+		}
+		switch keyword {
+		case "minLength":
+			t.minLength = &num
+		case "maxLength":
+			t.maxLength = &num
+		case "minItems":
+			t.minItems = &num
+		case "maxItems":
+			t.maxItems = &num
+		case "minContains":
+			t.minContains = &num
+		case "maxContains":
+			t.maxContains = &num
+		case "minProperties":
+			t.minProperties = &num
+		case "maxProperties":
+			t.maxProperties = &num
+		}
+	case "pattern", "patternProperties":
+		re, err := regexp.Compile(value)
 		if err != nil {
-			return ErrTagSyntax
+			return valueError(fmt.Sprintf("invalid regexp: %s", err))
 		}
-		t.maxItems = &num
-		if !r.IsEOF() {
-			t.read(r)
+		if keyword == "pattern" {
+			t.pattern = re
+		} else {
+			t.patternProperties = re
 		}
-	case bytes.Equal(prefix[:], preUniqueItems):
-		prefix = r.ReadBytes(7)
-		if !bytes.Equal(prefix[:], uniqueItems) {
-			return ErrTagSyntax
+	case "format":
+		if value == "" {
+			return valueError("empty format")
 		}
-		r.SkipDelimiter()
-		value := r.ReadSeparator()
+		t.format = &value
+	case "uniqueItems":
 		uniq, err := strconv.ParseBool(value)
 		if err != nil {
-			return ErrTagSyntax
+			return valueError(fmt.Sprintf("invalid boolean %q", value))
 		}
 		t.uniqueItems = &uniq
-		if !r.IsEOF() {
-			t.read(r)
+	case "type":
+		list := []string{raw}
+		if raw == "" {
+			if list, err = r.ReadList(); err != nil {
+				return valueError(err.Error())
+			}
 		}
-	case bytes.Equal(prefix[:], preMinProperties):
-		prefix = r.ReadBytes(9)
-		if !bytes.Equal(prefix[:], properties) {
-			return ErrTagSyntax
+		for _, v := range list {
+			v, _ = unquote(v)
+			if !contains(jsonTypes, v) {
+				return valueError(fmt.Sprintf("unknown type %q", v))
+			}
+			t.types = append(t.types, v)
 		}
-		r.SkipDelimiter()
-		value := r.ReadSeparator()
-		num, err := strconv.ParseInt(value, 10, 64)
+	case "required":
+		list, err := r.ReadList()
 		if err != nil {
-			return ErrTagSyntax
-		}
-		t.minProperties = &num
-		if !r.IsEOF() {
-			t.read(r)
+			return valueError(err.Error())
 		}
-	case bytes.Equal(prefix[:], preMaxProperties):
-		prefix = r.ReadBytes(9)
-		if !bytes.Equal(prefix[:], properties) {
-			return ErrTagSyntax
+		for _, v := range list {
+			name, _ := unquote(v)
+			t.required = append(t.required, name)
 		}
-		r.SkipDelimiter()
-		value := r.ReadSeparator()
-		num, err := strconv.ParseInt(value, 10, 64)
+	case "enum":
+		list, err := r.ReadList()
 		if err != nil {
-			return ErrTagSyntax
-		}
-		t.maxProperties = &num
-		if !r.IsEOF() {
-			t.read(r)
-		}
-	case bytes.Equal(prefix[:], preRequired):
-		prefix = r.ReadBytes(4)
-		if !bytes.Equal(prefix[:], required) {
-			return ErrTagSyntax
-		}
-		r.SkipDelimiter()
-		buf := []byte{}
-		for {
-			b, err := r.ReadByte()
-			if err != nil {
-				return ErrTagSyntax
-			}
-			if b == byte('[') {
-				continue
-			}
-			if b == byte(']') {
-				v := strings.TrimSpace(string(buf))
-				t.required = append(t.required, v)
-				break
-			}
-			if b == byte(',') {
-				v := strings.TrimSpace(string(buf))
-				t.required = append(t.required, v)
-				buf = []byte{}
-				continue
-			}
-
-			buf = append(buf, b)
+			return valueError(err.Error())
 		}
-		if !r.IsEOF() {
-			t.read(r)
+		for _, v := range list {
+			t.enum = append(t.enum, parseEnumValue(v))
 		}
-	case bytes.Equal(prefix[:], preEnum):
-		r.SkipDelimiter()
-		buf := []byte{}
-		for {
-			b, err := r.ReadByte()
-			if err != nil {
-				return ErrTagSyntax
-			}
-			if b == byte('[') {
-				continue
-			}
-			if b == byte(']') {
-				t.enum = append(t.enum, string(buf))
-				break
-			}
-			if b == byte(',') {
-				t.enum = append(t.enum, string(buf))
-				buf = []byte{}
-				continue
-			}
+	case "const":
+		c := parseEnumValue(raw)
+		t.constValue = &c
+	}
+	return nil
+}
 
-			buf = append(buf, b)
+func (t *tag) offset(keyword string) int {
+	for _, kw := range t.keywords {
+		if kw.name == keyword {
+			return kw.offset
 		}
-		if !r.IsEOF() {
-			t.read(r)
+	}
+	return -1
+}
+
+// applicators returns the sub-tags applied to the same value as the tag.
+func (t *tag) applicators() []*tag {
+	subs := make([]*tag, 0, len(t.allOf)+len(t.anyOf)+len(t.oneOf)+4)
+	subs = append(subs, t.allOf...)
+	subs = append(subs, t.anyOf...)
+	subs = append(subs, t.oneOf...)
+	for _, sub := range []*tag{t.not, t.ifTag, t.thenTag, t.elseTag} {
+		if sub != nil {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// children returns every sub-tag of the tag.
+func (t *tag) children() []*tag {
+	subs := t.applicators()
+	subs = append(subs, t.prefixItems...)
+	for _, sub := range []*tag{t.items, t.contains, t.additionalProperties, t.keys, t.values} {
+		if sub != nil {
+			subs = append(subs, sub)
 		}
 	}
-	if !r.IsEOF() {
-		r.ReadByte()
-		t.read(r)
+	for _, p := range t.properties {
+		subs = append(subs, p.tag)
 	}
-	return nil
+	for _, p := range t.patterns {
+		subs = append(subs, p.tag)
+	}
+	for _, d := range t.dependentSchemas {
+		subs = append(subs, d.tag)
+	}
+	return subs
+}
+
+// hasNumberKeywords reports whether the tag has keywords checking numbers.
+func (t *tag) hasNumberKeywords() bool {
+	return t != nil && (t.minimum != nil || t.maximum != nil || t.exclusiveMinimumD6 != nil ||
+		t.exclusiveMaximumD6 != nil || t.multipleOf != nil)
+}
+
+// hasApplicators reports whether the tag has keywords which apply to any
+// kind of value.
+func (t *tag) hasApplicators() bool {
+	return len(t.types) > 0 || len(t.enum) > 0 || t.constValue != nil || t.format != nil || t.ref != nil || len(t.allOf) > 0 || len(t.anyOf) > 0 || len(t.oneOf) > 0 || t.not != nil || t.ifTag != nil
 }
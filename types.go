@@ -0,0 +1,321 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	jsonNumberType = reflect.TypeOf(json.Number(""))
+	bigIntType     = reflect.TypeOf(big.Int{})
+	bigFloatType   = reflect.TypeOf(big.Float{})
+	bigRatType     = reflect.TypeOf(big.Rat{})
+)
+
+// isNumberType reports whether values of type rt are numbers held in a
+// json.Number or a math/big type.
+func isNumberType(rt reflect.Type) bool {
+	return rt == jsonNumberType || rt == bigIntType || rt == bigFloatType || rt == bigRatType
+}
+
+// numberOf returns the number held by a value of a number type, or false
+// when it is not a finite number.
+func numberOf(value reflect.Value) (*big.Rat, bool) {
+	if value.Type() == jsonNumberType {
+		if value.Len() == 0 {
+			// encoding/json encodes an empty json.Number as 0
+			return new(big.Rat), true
+		}
+		return parseNumber(value.String())
+	}
+	if !value.CanInterface() {
+		return nil, false
+	}
+	if !value.CanAddr() {
+		// the math/big methods have pointer receivers
+		addressable := reflect.New(value.Type()).Elem()
+		addressable.Set(value)
+		value = addressable
+	}
+	switch num := value.Addr().Interface().(type) {
+	case *big.Int:
+		return new(big.Rat).SetInt(num), true
+	case *big.Float:
+		if num.IsInf() {
+			return nil, false
+		}
+		return parseNumber(num.Text('g', -1))
+	case *big.Rat:
+		return new(big.Rat).Set(num), true
+	}
+	return nil, false
+}
+
+// jsonTypes are the primitive types of JSON Schema.
+var jsonTypes = []string{"array", "boolean", "integer", "null", "number", "object", "string"}
+
+// jsonType returns the JSON type of the encoding of value, or "" when it
+// depends on a custom marshaler. Numbers with a zero fractional part are
+// integers.
+func jsonType(value reflect.Value) string {
+	if !value.IsValid() {
+		return "null"
+	}
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return "null"
+		}
+	case reflect.Map, reflect.Slice:
+		if value.IsNil() {
+			return "null"
+		}
+	}
+	if isNumberType(value.Type()) {
+		if num, ok := numberOf(value); ok && num.IsInt() {
+			return "integer"
+		}
+		return "number"
+	}
+	if value.Kind() == reflect.Float32 || value.Kind() == reflect.Float64 {
+		if f := value.Float(); f != math.Trunc(f) || math.IsInf(f, 0) {
+			return "number"
+		}
+		return "integer"
+	}
+	if value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		return jsonType(value.Elem())
+	}
+	return staticType(value.Type())
+}
+
+// staticType returns the JSON type of the encoding of values of type rt, or
+// "" when it depends on the value.
+func staticType(rt reflect.Type) string {
+	switch {
+	case rt == rawMessageType, rt == jsonNumberType:
+		return ""
+	case rt.Implements(jsonMarshalerType), reflect.PtrTo(rt).Implements(jsonMarshalerType):
+		return ""
+	case rt.Implements(textMarshalerType), reflect.PtrTo(rt).Implements(textMarshalerType):
+		return "string"
+	}
+	switch rt.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice:
+		if rt.Elem().Kind() == reflect.Uint8 {
+			// encoding/json encodes []byte as a base64 string
+			return "string"
+		}
+		return "array"
+	case reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+	return ""
+}
+
+// hasMarshaler reports whether encoding/json encodes values of type rt with
+// a custom marshaler.
+func hasMarshaler(rt reflect.Type) bool {
+	return rt.Implements(jsonMarshalerType) || reflect.PtrTo(rt).Implements(jsonMarshalerType) ||
+		rt.Implements(textMarshalerType) || reflect.PtrTo(rt).Implements(textMarshalerType)
+}
+
+// matchType reports whether a value of JSON type actual matches one of
+// types. Integers are numbers too.
+func matchType(types []string, actual string) bool {
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchStaticType reports whether some field of type rt can match one of
+// types.
+func matchStaticType(types []string, rt reflect.Type) bool {
+	nullable := false
+	for rt.Kind() == reflect.Ptr {
+		rt, nullable = rt.Elem(), true
+	}
+	if rt.Kind() == reflect.Map || rt.Kind() == reflect.Slice || rt.Kind() == reflect.Interface {
+		nullable = true
+	}
+	actual := staticType(rt)
+	if actual == "" || rt.Kind() == reflect.Interface || (nullable && contains(types, "null")) {
+		return true
+	}
+	if actual == "number" && contains(types, "integer") {
+		// floats hold integers too
+		return true
+	}
+	return matchType(types, actual)
+}
+
+// enumValue is a value of enum or const. Values written bare in tags, not
+// quoted, also match strings of the same text, as enum always did.
+type enumValue struct {
+	raw   string
+	value interface{} // decoded JSON with json.Number numbers
+	bare  bool
+}
+
+// parseEnumValue parses a value of a tag: a quoted string, or a JSON
+// literal. Other text is a string.
+func parseEnumValue(raw string) enumValue {
+	if s, quoted := unquote(raw); quoted {
+		return enumValue{raw: s, value: s}
+	}
+	value, err := decodeJSON([]byte(raw))
+	if err != nil {
+		return enumValue{raw: raw, value: raw, bare: true}
+	}
+	return enumValue{raw: raw, value: value, bare: !strings.HasPrefix(raw, `"`)}
+}
+
+func (e enumValue) match(value interface{}) bool {
+	if s, ok := value.(string); ok && e.bare && s == e.raw {
+		return true
+	}
+	return jsonEqual(e.value, value)
+}
+
+func (e enumValue) String() string {
+	return formatJSON(e.value)
+}
+
+// decodeJSON decodes a single JSON value with json.Number numbers.
+func decodeJSON(b []byte) (interface{}, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var value interface{}
+	if err := d.Decode(&value); err != nil {
+		return nil, err
+	}
+	if d.More() {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return value, nil
+}
+
+// jsonValue returns the JSON encoding of value decoded as by decodeJSON.
+func jsonValue(value reflect.Value) (interface{}, error) {
+	for (value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr) && !value.IsNil() {
+		value = value.Elem()
+	}
+	if !value.IsValid() || isNil(value) {
+		return nil, nil
+	}
+	switch {
+	case isNumberType(value.Type()):
+		num, ok := numberOf(value)
+		if !ok {
+			return nil, errors.New("invalid number")
+		}
+		return json.Number(formatNumber(num)), nil
+	case value.Type() == rawMessageType:
+		return decodeJSON(value.Bytes())
+	}
+	if !hasMarshaler(value.Type()) {
+		switch value.Kind() {
+		case reflect.Bool:
+			return value.Bool(), nil
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return json.Number(strconv.FormatInt(value.Int(), 10)), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return json.Number(strconv.FormatUint(value.Uint(), 10)), nil
+		case reflect.Float32:
+			return json.Number(strconv.FormatFloat(value.Float(), 'g', -1, 32)), nil
+		case reflect.Float64:
+			return json.Number(strconv.FormatFloat(value.Float(), 'g', -1, 64)), nil
+		case reflect.String:
+			return value.String(), nil
+		}
+	}
+	if !value.CanInterface() {
+		return nil, errors.New("unexported value")
+	}
+	b, err := json.Marshal(value.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSON(b)
+}
+
+// jsonEqual reports whether two decoded JSON values are equal. Numbers are
+// equal when their values are, as 1 and 1.0.
+func jsonEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case nil:
+		return b == nil
+	case bool:
+		v, ok := b.(bool)
+		return ok && a == v
+	case string:
+		v, ok := b.(string)
+		return ok && a == v
+	case json.Number:
+		v, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, ok := new(big.Rat).SetString(a.String())
+		y, ok2 := new(big.Rat).SetString(v.String())
+		return ok && ok2 && x.Cmp(y) == 0
+	case []interface{}:
+		v, ok := b.([]interface{})
+		if !ok || len(a) != len(v) {
+			return false
+		}
+		for i := range a {
+			if !jsonEqual(a[i], v[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		v, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(v) {
+			return false
+		}
+		for key, value := range a {
+			other, ok := v[key]
+			if !ok || !jsonEqual(value, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// formatJSON formats a decoded JSON value for messages. Strings are not
+// quoted.
+func formatJSON(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	b, _ := json.Marshal(value)
+	return string(b)
+}
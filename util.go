@@ -4,6 +4,7 @@ import (
 	"math/big"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
 func contains(strs []string, str string) bool {
@@ -35,6 +36,92 @@ func toString(value reflect.Value) string {
 	case reflect.Float32, reflect.Float64:
 		return big.NewFloat(value.Float()).String()
 	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
 	}
 	return ""
 }
+
+// parseNumber parses a decimal number exactly. Fractions such as 1/3 are not
+// JSON numbers and are rejected.
+func parseNumber(s string) (*big.Rat, bool) {
+	if strings.ContainsRune(s, '/') {
+		return nil, false
+	}
+	return new(big.Rat).SetString(s)
+}
+
+// floatNumber converts a float through its shortest decimal form, so 19.99
+// is 1999/100 rather than the nearest binary fraction.
+func floatNumber(f float64, bitSize int) (*big.Rat, bool) {
+	return parseNumber(strconv.FormatFloat(f, 'g', -1, bitSize))
+}
+
+// formatNumber formats num as a decimal, exactly when it has a finite
+// expansion.
+func formatNumber(num *big.Rat) string {
+	if num.IsInt() {
+		return num.Num().String()
+	}
+	// a fraction has a finite expansion when its denominator only has the
+	// factors 2 and 5, the larger count is the number of digits needed
+	d := new(big.Int).Set(num.Denom())
+	twos := int(d.TrailingZeroBits())
+	d.Rsh(d, uint(twos))
+	fives := 0
+	for q, m, five := new(big.Int), new(big.Int), big.NewInt(5); ; fives++ {
+		if q.QuoRem(d, five, m); m.Sign() != 0 {
+			break
+		}
+		d.Set(q)
+	}
+	if d.IsInt64() && d.Int64() == 1 {
+		if twos > fives {
+			return num.FloatString(twos)
+		}
+		return num.FloatString(fives)
+	}
+	return num.FloatString(16)
+}
+
+func joinName(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// isNil reports whether value is encoded as null by encoding/json.
+func isNil(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return value.IsNil()
+	}
+	return false
+}
+
+// isEmptyValue reports whether encoding/json omits value with omitempty.
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Bool:
+		return !value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return value.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return value.IsNil()
+	}
+	return false
+}
+
+// hasKey reports whether the map value has the string key.
+func hasKey(value reflect.Value, key string) bool {
+	if value.Type().Key().Kind() != reflect.String {
+		return false
+	}
+	return value.MapIndex(reflect.ValueOf(key).Convert(value.Type().Key())).IsValid()
+}
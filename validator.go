@@ -2,12 +2,14 @@ package jsonschema
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"math/big"
 	"reflect"
+	"sort"
 	"strconv"
-	"unicode"
+	"sync"
 	"unicode/utf8"
 )
 
@@ -19,37 +21,53 @@ const (
 type ValidationError struct {
 	Name    string
 	Message string
-	Causes  []*ValidationError
+	// InstancePath is the JSON Pointer to the invalid value, built from the
+	// json names of struct fields
+	InstancePath string
+	// KeywordLocation is the JSON Pointer to the failing keyword in the
+	// schema. For struct tags it is the location in the struct schema with
+	// nested structs inlined, without the $ref into definitions of the
+	// schema GenerateSchema emits
+	KeywordLocation string
+	Keyword         string
+	// Code identifies the check that failed, see the Code constants
+	Code string
+	// Params holds the values of the message, such as the actual value and
+	// the limit
+	Params map[string]interface{}
+	Causes []*ValidationError
 }
 
-// Error -
+// Error returns the messages of the error and its causes, separated by
+// "; ".
 func (v *ValidationError) Error() string {
-	buf := bytes.NewBufferString(v.Message)
-	write(buf, v)
-	return buf.String()
-}
-
-func write(buf *bytes.Buffer, e *ValidationError) {
-	for _, err := range e.Causes {
-		if err.Message != "" {
-			buf.WriteString(err.Message)
-		}
-		if len(err.Causes) > 0 {
-			write(buf, err)
+	buf := &bytes.Buffer{}
+	v.walk(func(e *ValidationError) {
+		if buf.Len() > 0 {
+			buf.WriteString("; ")
 		}
-	}
+		buf.WriteString(e.Message)
+	})
+	return buf.String()
 }
 
 func (v *ValidationError) isEmpty() bool {
-	return v.Name == "" && v.Message == "" && len(v.Causes) == 0
+	return v == nil || (v.Name == "" && v.Message == "" && v.Code == "" && len(v.Causes) == 0)
 }
 
 func (v *ValidationError) add(err *ValidationError) {
-	if err != nil {
+	if v != nil && err != nil {
 		v.Causes = append(v.Causes, err)
 	}
 }
 
+// merge adds the causes of err.
+func (v *ValidationError) merge(err *ValidationError) {
+	if v != nil && err != nil {
+		v.Causes = append(v.Causes, err.Causes...)
+	}
+}
+
 func newValidationError() *ValidationError {
 	return &ValidationError{
 		Causes: []*ValidationError{},
@@ -57,8 +75,8 @@ func newValidationError() *ValidationError {
 }
 
 // NewValidator -
-func NewValidator() *Validator {
-	return &Validator{
+func NewValidator(opts ...Option) *Validator {
+	v := &Validator{
 		formats: map[string]ValidateFunc{
 			// Defined formats
 			"date-time":     dateTime,
@@ -72,6 +90,185 @@ func NewValidator() *Validator {
 			"json-pointer":  jsonPointer,
 		},
 	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// state is the state of one validation walk.
+type state struct {
+	// applicator is set while checking the sub-tags of a value. The fields
+	// of structs are validated by their own tags once, not by every sub-tag.
+	applicator bool
+	// flag is set by IsValid: errors are only counted, not built
+	flag bool
+	// max is the number of errors stopping the walk, 0 for no limit
+	max   int
+	count int
+	// err is the first error which is not a ValidationError, such as the
+	// TagError of a nested struct. It stops the walk and is returned instead
+	// of the validation errors.
+	err error
+	// scratch numbers, so that valid integers need no allocation
+	num big.Rat
+	rem big.Int
+	// entries are the values map entries are copied into by flag walks, by
+	// map type
+	entries map[reflect.Type][][2]reflect.Value
+}
+
+// entry returns values to copy the entries of a map of type rt into.
+func (s *state) entry(rt reflect.Type) (reflect.Value, reflect.Value) {
+	if free := s.entries[rt]; len(free) > 0 {
+		s.entries[rt] = free[:len(free)-1]
+		return free[len(free)-1][0], free[len(free)-1][1]
+	}
+	return reflect.New(rt.Key()).Elem(), reflect.New(rt.Elem()).Elem()
+}
+
+// putEntry keeps the values of entry for the next map of type rt, without
+// holding on to the last entry.
+func (s *state) putEntry(rt reflect.Type, key, elem reflect.Value) {
+	key.Set(reflect.Zero(rt.Key()))
+	elem.Set(reflect.Zero(rt.Elem()))
+	if s.entries == nil {
+		s.entries = map[reflect.Type][][2]reflect.Value{}
+	}
+	s.entries[rt] = append(s.entries[rt], [2]reflect.Value{key, elem})
+}
+
+// full reports whether the walk has found as many errors as it may.
+func (s *state) full() bool {
+	return s.err != nil || (s.max > 0 && s.count >= s.max)
+}
+
+// check returns the validation errors of err. Other errors are kept in
+// s.err.
+func (s *state) check(err error) *ValidationError {
+	ret, ok := err.(*ValidationError)
+	if !ok && err != nil {
+		if s.err == nil {
+			s.err = err
+		}
+		return nil
+	}
+	if ret.isEmpty() {
+		return nil
+	}
+	return ret
+}
+
+// done returns the error of the walk, err of the value it started with.
+func (s *state) done(err error) error {
+	if s.err != nil {
+		return s.err
+	}
+	return err
+}
+
+// result returns the error collecting the errors of a value, nil in flag
+// walks.
+func (s *state) result() *ValidationError {
+	if s.flag {
+		return nil
+	}
+	return newValidationError()
+}
+
+// error counts and returns the error of keyword of the tag at path. It
+// returns nil once the walk is full, and in flag walks.
+func (s *state) error(path evalPath, keyword, code string, params map[string]interface{}, causes ...*ValidationError) *ValidationError {
+	if s.full() {
+		return nil
+	}
+	s.count++
+	if s.flag {
+		return nil
+	}
+	return path.error(keyword, code, params, causes...)
+}
+
+// flagStates are the states of IsValid, kept for their scratch numbers.
+var flagStates = sync.Pool{
+	New: func() interface{} {
+		return &state{flag: true, max: 1}
+	},
+}
+
+// evalPath is the position of a value in the instance and of its tag in the
+// schema, both as JSON Pointers.
+type evalPath struct {
+	name     string // Go name of the value, see ValidationError.Name
+	instance string
+	keyword  string
+	off      bool // set by IsValid, which builds no paths
+}
+
+// child returns the path of the value token inside the value at p, named
+// name.
+func (p evalPath) child(name, token string) evalPath {
+	if p.off {
+		return p
+	}
+	p.name = name
+	p.instance += "/" + escapePointer(token)
+	return p
+}
+
+// field returns the path of the struct field or map property name, token
+// in JSON.
+func (p evalPath) field(name, token string) evalPath {
+	if p.off {
+		return p
+	}
+	return p.child(joinName(p.name, name), token)
+}
+
+// index returns the path of the element i of the array at p.
+func (p evalPath) index(i int) evalPath {
+	if p.off {
+		return p
+	}
+	return p.child(fmt.Sprintf("%s[%d]", p.name, i), strconv.Itoa(i))
+}
+
+// entry returns the path of the key or the value, by part, of the entry key
+// of the map at p.
+func (p evalPath) entry(key reflect.Value, part string) evalPath {
+	if p.off {
+		return p
+	}
+	return p.child(fmt.Sprintf("%s[%v](%s)", p.name, key.Interface(), part), toString(key))
+}
+
+// schema returns the path of the sub-tag found below the tag at p through
+// tokens.
+func (p evalPath) schema(tokens ...string) evalPath {
+	if p.off {
+		return p
+	}
+	for _, token := range tokens {
+		p.keyword += "/" + escapePointer(token)
+	}
+	return p
+}
+
+// error returns the error of keyword of the tag at p.
+func (p evalPath) error(keyword, code string, params map[string]interface{}, causes ...*ValidationError) *ValidationError {
+	ret := &ValidationError{
+		Name:            p.name,
+		Code:            code,
+		Params:          params,
+		InstancePath:    p.instance,
+		KeywordLocation: p.keyword,
+		Keyword:         keyword,
+		Causes:          causes,
+	}
+	if keyword != "" {
+		ret.KeywordLocation += "/" + keyword
+	}
+	return ret
 }
 
 // ValidateFunc -
@@ -79,7 +276,49 @@ type ValidateFunc func(data *reflect.Value, field *reflect.StructField) error
 
 // Validator -
 type Validator struct {
-	formats map[string]ValidateFunc
+	draft        Draft
+	requiredMode RequiredMode
+	maxErrors    int
+	loader       Loader
+	locale       string
+	formats      map[string]ValidateFunc
+	messages     map[string]map[string]*message // locale -> code
+	plans        sync.Map                       // reflect.Type -> *planEntry
+}
+
+// RequiredMode -
+type RequiredMode int
+
+// Required modes, deciding when a field tagged with required is missing. A
+// field with omitempty is missing whenever encoding/json omits it.
+const (
+	// RequiredNonNil rejects nil pointers, interfaces, maps and slices
+	RequiredNonNil RequiredMode = iota
+	// RequiredNullable accepts nil values, encoded as null
+	RequiredNullable
+	// RequiredNonZero rejects nil and zero values
+	RequiredNonZero
+)
+
+// WithRequiredMode -
+func WithRequiredMode(m RequiredMode) Option {
+	return func(v *Validator) {
+		v.requiredMode = m
+	}
+}
+
+// WithFailFast makes Validate stop at the first error.
+func WithFailFast() Option {
+	return WithMaxErrors(1)
+}
+
+// WithMaxErrors makes Validate stop once it has found n errors. Keywords
+// with sub-schemas, such as anyOf, count as one error. n <= 0 means no
+// limit.
+func WithMaxErrors(n int) Option {
+	return func(v *Validator) {
+		v.maxErrors = n
+	}
 }
 
 // AddFormat -
@@ -94,6 +333,18 @@ func (v *Validator) AddFormat(key string, f ValidateFunc) error {
 	return nil
 }
 
+// formatApplies reports whether the format of tag checks value.
+func (v *Validator) formatApplies(tag *tag, value reflect.Value) bool {
+	if !value.IsValid() {
+		return false
+	}
+	if !tag.stringFormat {
+		return true
+	}
+	_, ok := v.formats[*tag.format]
+	return ok && value.Kind() == reflect.String && !isNumberType(value.Type())
+}
+
 func (v *Validator) execFormat(key string, value *reflect.Value, field *reflect.StructField) error {
 	f, ok := v.formats[key]
 	if !ok {
@@ -104,55 +355,86 @@ func (v *Validator) execFormat(key string, value *reflect.Value, field *reflect.
 
 // Validate -
 func (v *Validator) Validate(data interface{}) error {
-	rt, rv := reflect.TypeOf(data), reflect.ValueOf(data)
+	return v.ValidateContext(context.Background(), data)
+}
 
-	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
-		return v.Validate(rv.Elem().Interface())
+// ValidateContext validates data with the messages of the locale picked by
+// ContextWithLocale. data must be a struct or a pointer to one; JSON
+// documents and decoded values are validated by a Schema, see Compile.
+func (v *Validator) ValidateContext(ctx context.Context, data interface{}) error {
+	rv := reflect.ValueOf(data)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
 	}
-	if rv.Kind() != reflect.Struct && rv.Kind() != reflect.Interface {
-		return errors.New("")
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("jsonschema: Validate expects a struct, got %T; validate JSON with Compile and Schema.Validate", data)
 	}
+	s := &state{max: v.maxErrors}
+	return v.localize(s.done(v.validateStruct(s, rv, evalPath{})), v.localeOf(ctx))
+}
 
-	result := newValidationError()
-	for i := 0; i < rt.NumField(); i++ {
-		field := rt.Field(i)
-		name := field.Name
-		if !unicode.IsUpper(rune(name[0])) {
-			continue
-		}
+// IsValid reports whether data is valid. It stops at the first error and
+// builds neither errors nor paths, so that it allocates nothing for valid
+// structs of strings, integers, booleans, slices and nested structs.
+// Keywords needing the JSON of a value, such as enum and uniqueItems,
+// formats, floats, maps and the failing sub-tags of anyOf, oneOf, not and
+// if may still allocate. data which is not a struct is not valid, as it is
+// an error for Validate.
+func (v *Validator) IsValid(data interface{}) bool {
+	rv := reflect.ValueOf(data)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return false
+	}
+	s := flagStates.Get().(*state)
+	s.applicator, s.count, s.err = false, 0, nil
+	err := s.done(v.validateStruct(s, rv, evalPath{off: true}))
+	valid := s.count == 0
+	s.err = nil
+	flagStates.Put(s)
+	if _, ok := err.(*ValidationError); !ok && err != nil {
+		return false
+	}
+	return valid
+}
 
-		tagValue := field.Tag.Get(tagName)
-		if tagValue == "-" {
-			continue
-		}
+func (v *Validator) validateStruct(s *state, rv reflect.Value, path evalPath) error {
+	plan, err := v.plan(rv.Type())
+	if err != nil {
+		return err
+	}
 
-		tag, err := v.parseTag(tagValue)
-		if err != nil {
-			return err
+	result := s.result()
+	for i := range plan.fields {
+		if s.full() {
+			break
 		}
+		f := &plan.fields[i]
+		fieldPath, tag := path.field(f.name, f.token).schema("properties", f.token), f.tag
 
-		value := rv.Field(i)
+		value := rv.Field(f.index)
+		if tag != nil && tag.fieldRequired {
+			// required is a keyword of the struct
+			requiredPath := fieldPath
+			requiredPath.keyword = path.keyword
+			if ret := v.validateRequired(s, value, requiredPath, f.omitempty, tag.nullable); ret != nil {
+				result.add(ret)
+				continue
+			}
+		}
 		if value.Kind() == reflect.Ptr && !value.IsNil() {
 			value = value.Elem()
 		}
 
-		if tag != nil && tag.format != nil {
-			if e := v.execFormat(*tag.format, &value, &field); e != nil {
-				result.add(&ValidationError{
-					Message: fmt.Sprintf("Format validation failed (%s)", e.Error()),
-					Name:    name,
-				})
-			}
-		}
-
-		err = v.validate(value, name, tag)
-		if err == nil {
-			continue
-		}
-		ret, ok := err.(*ValidationError)
-		if ok && ret != nil && !ret.isEmpty() {
-			result.add(ret)
+		result.add(s.check(v.validate(s, value, fieldPath, &f.field, tag)))
+	}
+	for _, tag := range plan.tags {
+		if s.full() {
+			break
 		}
+		result.add(v.validateTag(s, rv, path, nil, tag))
 	}
 
 	if result.isEmpty() {
@@ -161,236 +443,556 @@ func (v *Validator) Validate(data interface{}) error {
 	return result
 }
 
-func (v *Validator) parseTag(meta string) (*tag, error) {
+// validateRequired checks that a required field is present in the JSON
+// encoding of its struct: fields with omitempty are omitted when empty, and
+// nil pointers, interfaces, maps and slices are encoded as null.
+func (v *Validator) validateRequired(s *state, value reflect.Value, path evalPath, omitempty, nullable bool) *ValidationError {
+	switch {
+	case omitempty && isEmptyValue(value):
+		return s.error(path, "required", CodeRequired, nil)
+	case isNil(value):
+		if nullable || v.requiredMode == RequiredNullable {
+			return nil
+		}
+		return s.error(path, "required", CodeNotNull, nil)
+	case v.requiredMode == RequiredNonZero && value.IsZero():
+		return s.error(path, "required", CodeNotZero, nil)
+	}
+	return nil
+}
+
+func (v *Validator) parseTag(meta string) (*tag, *TagError) {
 	tag := newTag()
 	r := newReader([]byte(meta))
-	err := tag.read(r)
-	return tag, err
+	if err := tag.read(r); err != nil {
+		return nil, err
+	}
+	tag.inherit()
+	if err := tag.checkDraft(v.draft); err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+func (v *Validator) validate(s *state, value reflect.Value, path evalPath, field *reflect.StructField, tag *tag) error {
+	for (value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr) && !value.IsNil() {
+		value = value.Elem()
+	}
+	if value.IsValid() && value.Type() == rawMessageType {
+		// validate the JSON the field holds
+		var data interface{}
+		if value.Len() > 0 {
+			var err error
+			if data, err = decodeJSON(value.Bytes()); err != nil {
+				return s.error(path, "", CodeInvalidJSON, map[string]interface{}{"error": err.Error()})
+			}
+		}
+		value = reflect.ValueOf(&data).Elem()
+		for (value.Kind() == reflect.Interface || value.Kind() == reflect.Ptr) && !value.IsNil() {
+			value = value.Elem()
+		}
+	}
+	if tag != nil && tag.falseSchema {
+		return s.error(path, "", CodeFalseSchema, nil)
+	}
+
+	err := v.validateKind(s, value, path, field, tag)
+	if tag == nil || !tag.hasApplicators() {
+		return err
+	}
+
+	result := s.result()
+	v.validateApplicators(s, result, value, path, field, tag)
+	ret, ok := err.(*ValidationError)
+	if !ok && err != nil {
+		return err
+	}
+	result.merge(ret)
+	if (len(tag.enum) > 0 || tag.constValue != nil) && !tag.inheritedBy(value) {
+		v.validateEnum(s, result, value, path, tag)
+	}
+	return result
+}
+
+// validateEnum checks enum and const by JSON equality.
+func (v *Validator) validateEnum(s *state, result *ValidationError, value reflect.Value, path evalPath, tag *tag) {
+	data, err := jsonValue(value)
+	if err != nil {
+		result.add(s.error(path, "", CodeInvalidValue, map[string]interface{}{"error": err.Error()}))
+		return
+	}
+	if tag.constValue != nil && !tag.constValue.match(data) {
+		result.add(s.error(path, "const", CodeConst, map[string]interface{}{"actual": data, "const": tag.constValue.value}))
+	}
+	if len(tag.enum) > 0 {
+		for _, e := range tag.enum {
+			if e.match(data) {
+				return
+			}
+		}
+		values := make([]interface{}, len(tag.enum))
+		for i, e := range tag.enum {
+			values[i] = e.value
+		}
+		result.add(s.error(path, "enum", CodeEnum, map[string]interface{}{"actual": data, "enum": values}))
+	}
+}
+
+// validateTag returns the errors of value against the sub-tag of a keyword,
+// or nil if it is valid.
+func (v *Validator) validateTag(s *state, value reflect.Value, path evalPath, field *reflect.StructField, tag *tag) *ValidationError {
+	if tag == nil {
+		return nil
+	}
+	applicator := s.applicator
+	s.applicator = true
+	ret := s.check(v.validate(s, value, path, field, tag))
+	s.applicator = applicator
+	return ret
+}
+
+// match reports whether value is valid against the sub-tag of a keyword
+// which may drop its errors, such as anyOf, and returns them if not. They
+// are counted apart from the errors of the walk.
+func (v *Validator) match(s *state, value reflect.Value, path evalPath, field *reflect.StructField, tag *tag) (*ValidationError, bool) {
+	count := s.count
+	s.count = 0
+	ret := v.validateTag(s, value, path, field, tag)
+	ok := s.count == 0
+	s.count = count
+	return ret, ok
+}
+
+// validateApplicators checks the keywords which apply to any kind of value.
+func (v *Validator) validateApplicators(s *state, result *ValidationError, value reflect.Value, path evalPath, field *reflect.StructField, tag *tag) {
+	if len(tag.types) > 0 {
+		if actual := jsonType(value); actual != "" && !matchType(tag.types, actual) {
+			result.add(s.error(path, "type", CodeType, map[string]interface{}{"actual": actual, "expected": tag.types}))
+		}
+	}
+	if tag.format != nil && v.formatApplies(tag, value) {
+		// a copy, so that value itself does not escape
+		formatted := value
+		if e := v.execFormat(*tag.format, &formatted, field); e != nil {
+			result.add(s.error(path, "format", CodeFormat, map[string]interface{}{"format": *tag.format, "error": e.Error()}))
+		}
+	}
+	if tag.ref != nil {
+		result.add(v.validateTag(s, value, path.schema("$ref"), field, tag.ref))
+	}
+	for i, sub := range tag.allOf {
+		result.merge(v.validateTag(s, value, path.schema("allOf", strconv.Itoa(i)), field, sub))
+	}
+	if len(tag.anyOf) > 0 {
+		causes, matched := []*ValidationError{}, false
+		for i, sub := range tag.anyOf {
+			ret, ok := v.match(s, value, path.schema("anyOf", strconv.Itoa(i)), field, sub)
+			if ok {
+				matched = true
+				break
+			}
+			if ret != nil {
+				causes = append(causes, ret)
+			}
+		}
+		if !matched {
+			result.add(s.error(path, "anyOf", CodeAnyOf, nil, causes...))
+		}
+	}
+	if len(tag.oneOf) > 0 {
+		causes := []*ValidationError{}
+		matched, n := [2]int{}, 0
+		for i, sub := range tag.oneOf {
+			ret, ok := v.match(s, value, path.schema("oneOf", strconv.Itoa(i)), field, sub)
+			if !ok {
+				if ret != nil {
+					causes = append(causes, ret)
+				}
+				continue
+			}
+			matched[n] = i
+			if n++; n == len(matched) {
+				break
+			}
+		}
+		switch {
+		case n == 0:
+			result.add(s.error(path, "oneOf", CodeOneOf, nil, causes...))
+		case n > 1:
+			result.add(s.error(path, "oneOf", CodeOneOfMultiple, map[string]interface{}{"indices": matched[:]}))
+		}
+	}
+	if tag.not != nil {
+		if _, ok := v.match(s, value, path.schema("not"), field, tag.not); ok {
+			result.add(s.error(path, "not", CodeNot, nil))
+		}
+	}
+	if tag.ifTag != nil {
+		if _, ok := v.match(s, value, path.schema("if"), field, tag.ifTag); ok {
+			if ret := v.validateTag(s, value, path.schema("then"), field, tag.thenTag); ret != nil {
+				result.add(path.error("then", CodeThen, nil, ret))
+			}
+		} else if ret := v.validateTag(s, value, path.schema("else"), field, tag.elseTag); ret != nil {
+			result.add(path.error("else", CodeElse, nil, ret))
+		}
+	}
 }
 
-func (v *Validator) validate(value reflect.Value, fieldName string, tag *tag) error {
+func (v *Validator) validateKind(s *state, value reflect.Value, path evalPath, field *reflect.StructField, tag *tag) error {
+	if value.IsValid() && isNumberType(value.Type()) {
+		num, ok := numberOf(value)
+		if !ok {
+			text := toString(value)
+			if value.CanAddr() && value.CanInterface() {
+				text = fmt.Sprint(value.Addr().Interface())
+			}
+			return s.error(path, "", CodeInvalidNumber, map[string]interface{}{"actual": text})
+		}
+		if ret := v.validateNumber(s, num, path, tag); !ret.isEmpty() {
+			return ret
+		}
+		return nil
+	}
 	switch value.Kind() {
 	case reflect.Struct:
-		return v.Validate(value.Interface())
+		if tag == nil || (len(tag.properties) == 0 && len(tag.required) == 0) {
+			if s.applicator {
+				return nil
+			}
+			return v.validateStruct(s, value, path)
+		}
+		result := s.result()
+		if !s.applicator {
+			err := v.validateStruct(s, value, path)
+			ret, ok := err.(*ValidationError)
+			if !ok && err != nil {
+				return err
+			}
+			result.merge(ret)
+		}
+		plan, err := v.plan(value.Type())
+		if err != nil {
+			return err
+		}
+		for _, name := range tag.required {
+			i, ok := plan.names[name]
+			if !ok {
+				result.add(s.error(path.field(name, name), "required", CodeRequired, nil))
+				continue
+			}
+			field := value.Type().Field(i)
+			_, omitempty, _ := jsonName(field)
+			result.add(v.validateRequired(s, value.Field(i), path.field(name, fieldToken(field)), omitempty, false))
+		}
+		// the fields were validated with their own tags already
+		for _, p := range tag.properties {
+			if i, ok := plan.names[p.name]; ok {
+				propertyPath := path.field(p.name, fieldToken(value.Type().Field(i))).schema("properties", p.name)
+				result.add(v.validateTag(s, value.Field(i), propertyPath, nil, p.tag))
+			}
+		}
+		return result
 	case reflect.Map:
-		result := newValidationError()
+		result := s.result()
 		if tag != nil && (tag.minProperties != nil || tag.maxProperties != nil) {
 			l := int64(value.Len())
 			if tag.minProperties != nil && l < *tag.minProperties {
-				result.add(&ValidationError{
-					Message: fmt.Sprintf("Too few properties defined (%d), minimum %d", l, *tag.minProperties),
-					Name:    fieldName,
-				})
+				result.add(s.error(path, "minProperties", CodeMinProperties, map[string]interface{}{"actual": l, "limit": *tag.minProperties}))
 			}
 			if tag.maxProperties != nil && l > *tag.maxProperties {
-				result.add(&ValidationError{
-					Message: fmt.Sprintf("Too many properties defined (%d), maximum %d", l, *tag.maxProperties),
-					Name:    fieldName,
-				})
+				result.add(s.error(path, "maxProperties", CodeMaxProperties, map[string]interface{}{"actual": l, "limit": *tag.maxProperties}))
 			}
 		}
 		if tag != nil && len(tag.required) > 0 {
 			missing := []string{}
 			for _, req := range tag.required {
-				key := reflect.ValueOf(req)
-				if !value.MapIndex(key).IsValid() {
+				if !hasKey(value, req) {
 					missing = append(missing, req)
 				}
 			}
 			if len(missing) > 0 {
-				result.add(&ValidationError{
-					Message: fmt.Sprintf("Missing required property: %v", tag.required),
-					Name:    fieldName,
-				})
+				result.add(s.error(path, "required", CodeRequired, map[string]interface{}{"required": tag.required, "missing": missing}))
 			}
 		}
-		for _, key := range value.MapKeys() {
-			if tag != nil && tag.patternProperties != nil && !tag.patternProperties.MatchString(toString(key)) {
-				result.add(&ValidationError{
-					Message: fmt.Sprintf("Properties does not match pattern: %s", tag.patternProperties.String()),
-					Name:    fieldName,
-				})
+		if tag != nil && len(tag.properties) > 0 && value.Type().Key().Kind() == reflect.String {
+			for _, p := range tag.properties {
+				data := value.MapIndex(reflect.ValueOf(p.name).Convert(value.Type().Key()))
+				if !data.IsValid() {
+					continue
+				}
+				// the values are walked below
+				propertyPath := path.field(p.name, p.name).schema("properties", p.name)
+				result.add(v.validateTag(s, data, propertyPath, nil, p.tag))
 			}
-			if key.Kind() == reflect.Ptr && !key.IsNil() {
-				key = key.Elem()
+		}
+		if tag != nil && len(tag.dependentRequired) > 0 {
+			for _, d := range tag.dependentRequired {
+				if !hasKey(value, d.name) {
+					continue
+				}
+				for _, req := range d.required {
+					if !hasKey(value, req) {
+						result.add(s.error(path, "dependentRequired", CodeDependentRequired, map[string]interface{}{"property": req, "dependent": d.name}))
+					}
+				}
 			}
-			err := v.validate(key, fmt.Sprintf("%s[%v](key)", fieldName, key.Interface()), nil)
-			ret, ok := err.(*ValidationError)
-			if ok && ret != nil && !ret.isEmpty() {
-				result.add(ret)
+		}
+		if tag != nil && len(tag.dependentSchemas) > 0 {
+			for _, d := range tag.dependentSchemas {
+				if hasKey(value, d.name) {
+					result.add(v.validateTag(s, value, path.schema("dependentSchemas", d.name), field, d.tag))
+				}
 			}
-
-			data := value.MapIndex(key)
-			if data.Kind() == reflect.Ptr && !data.IsNil() {
-				data = data.Elem()
+		}
+		if s.flag {
+			// no errors are built, so the entries need no order
+			key, elem := s.entry(value.Type())
+			var iter reflect.MapIter
+			iter.Reset(value)
+			for !s.full() && iter.Next() {
+				key.SetIterKey(&iter)
+				elem.SetIterValue(&iter)
+				v.validateEntry(s, result, key, elem, path, field, tag)
 			}
-			err = v.validate(data, fmt.Sprintf("%s[%v](value)", fieldName, key.Interface()), nil)
-			ret, ok = err.(*ValidationError)
-			if ok && ret != nil && !ret.isEmpty() {
-				ret.add(ret)
+			s.putEntry(value.Type(), key, elem)
+			return result
+		}
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return toString(keys[i]) < toString(keys[j])
+		})
+		for _, key := range keys {
+			if s.full() {
+				break
 			}
+			v.validateEntry(s, result, key, value.MapIndex(key), path, field, tag)
 		}
 		return result
 	case reflect.Slice, reflect.Array:
-		result := newValidationError()
+		result := s.result()
 		l := value.Len()
 		if tag != nil && (tag.minItems != nil || tag.maxItems != nil) {
 			l := int64(l)
 			if tag.minItems != nil && l < *tag.minItems {
-				result.add(&ValidationError{
-					Message: fmt.Sprintf("Array is too short (%d), minimum %d", l, *tag.minItems),
-					Name:    fieldName,
-				})
+				result.add(s.error(path, "minItems", CodeMinItems, map[string]interface{}{"actual": l, "limit": *tag.minItems}))
 			}
 			if tag.maxItems != nil && l > *tag.maxItems {
-				result.add(&ValidationError{
-					Message: fmt.Sprintf("Array is too long (%d), maximum %d", l, *tag.maxItems),
-					Name:    fieldName,
-				})
+				result.add(s.error(path, "maxItems", CodeMaxItems, map[string]interface{}{"actual": l, "limit": *tag.maxItems}))
 			}
 		}
 		if tag != nil && tag.uniqueItems != nil && *tag.uniqueItems {
-			for i := 1; i < l; i++ {
+			items := make([]interface{}, l)
+			for i := range items {
+				items[i], _ = jsonValue(value.Index(i))
+			}
+			for i := 1; i < l && !s.full(); i++ {
 				for j := 0; j < i; j++ {
-					if value.Index(i).Interface() == value.Index(j).Interface() {
-						result.add(&ValidationError{
-							Message: fmt.Sprintf("Array items are not unique (indices %d and %d)", i, j),
-							Name:    fieldName,
-						})
+					if jsonEqual(items[i], items[j]) {
+						result.add(s.error(path, "uniqueItems", CodeUniqueItems, map[string]interface{}{"indices": []int{i, j}}))
 					}
 				}
 			}
 		}
-		// todo... contains tag
-		for i := 0; i < l; i++ {
-			err := v.validate(value.Index(i), fmt.Sprintf("%s[%d]", fieldName, i), tag)
-			ret, ok := err.(*ValidationError)
-			if ok && ret != nil && !ret.isEmpty() {
+		if tag != nil && tag.contains != nil {
+			if ret := v.validateContains(s, value, path, field, tag); ret != nil {
 				result.add(ret)
 			}
 		}
+		for i := 0; i < l && !s.full(); i++ {
+			itemPath := path.index(i)
+			if tag != nil && i < len(tag.prefixItems) {
+				itemPath = itemPath.schema("prefixItems", strconv.Itoa(i))
+			}
+			itemTag := tag.itemTag(i)
+			if itemTag != nil && itemTag.falseSchema {
+				result.add(s.error(itemPath, "items", CodeAdditionalItems, map[string]interface{}{"index": i}))
+				continue
+			}
+			if tag == nil || i >= len(tag.prefixItems) {
+				itemPath = itemPath.schema("items")
+			}
+			result.add(s.check(v.validate(s, value.Index(i), itemPath, field, itemTag)))
+		}
 		return result
 	case reflect.String:
 		str := value.String()
-		if ret := v.validateString(str, fieldName, tag); !ret.isEmpty() {
+		if ret := v.validateString(s, str, path, tag); !ret.isEmpty() {
 			return ret
 		}
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		str := strconv.FormatInt(value.Int(), 10)
-		num, flag := new(big.Float).SetString(str)
-		if !flag {
-			return errors.New("invalid integer")
-		}
-		if ret := v.validateNumber(num, fieldName, tag); !ret.isEmpty() {
+		num := s.num.SetInt64(value.Int())
+		if ret := v.validateNumber(s, num, path, tag); !ret.isEmpty() {
 			return ret
 		}
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		str := strconv.FormatUint(value.Uint(), 10)
-		num, flag := new(big.Float).SetString(str)
-		if !flag {
-			return errors.New("invalid unsigned integer")
-		}
-		if ret := v.validateNumber(num, fieldName, tag); !ret.isEmpty() {
+		num := s.num.SetUint64(value.Uint())
+		if ret := v.validateNumber(s, num, path, tag); !ret.isEmpty() {
 			return ret
 		}
 	case reflect.Float32, reflect.Float64:
-		num := big.NewFloat(value.Float())
-		if ret := v.validateNumber(num, fieldName, tag); !ret.isEmpty() {
+		if !tag.hasNumberKeywords() {
+			break
+		}
+		num, ok := floatNumber(value.Float(), value.Type().Bits())
+		if !ok {
+			// ±Inf and NaN have no JSON encoding
+			text := strconv.FormatFloat(value.Float(), 'g', -1, value.Type().Bits())
+			return s.error(path, "", CodeInvalidNumber, map[string]interface{}{"actual": text})
+		}
+		if ret := v.validateNumber(s, num, path, tag); !ret.isEmpty() {
 			return ret
 		}
+	case reflect.Bool:
+		// booleans have no keywords of their own, enum, const and format
+		// apply to every kind
 	}
 	return nil
 }
 
-func (v *Validator) validateString(str, fieldName string, tag *tag) *ValidationError {
-	ret := newValidationError()
+// validateEntry checks the key and the value of an entry of a map.
+func (v *Validator) validateEntry(s *state, result *ValidationError, key, elem reflect.Value, path evalPath, field *reflect.StructField, tag *tag) {
+	if tag != nil && (len(tag.patterns) > 0 || tag.additionalProperties != nil) {
+		v.validateAdditionalProperty(s, result, elem, toString(key), path, tag)
+	}
+	if tag != nil && tag.patternProperties != nil && !tag.patternProperties.MatchString(toString(key)) {
+		result.add(s.error(path, "patternProperties", CodePropertyPattern, map[string]interface{}{"pattern": tag.patternProperties.String()}))
+	}
+	keyPath := path.entry(key, "key").schema("propertyNames")
+	result.add(s.check(v.validate(s, key, keyPath, field, tag.keysTag())))
+
+	valuePath := path.entry(key, "value").schema("additionalProperties")
+	result.add(s.check(v.validate(s, elem, valuePath, field, tag.valuesTag())))
+}
+
+// validateAdditionalProperty checks a property of a map against the
+// patternProperties it matches, or else against additionalProperties.
+func (v *Validator) validateAdditionalProperty(s *state, result *ValidationError, data reflect.Value, name string, path evalPath, tag *tag) {
+	propertyPath := path.field(name, name)
+	matched := tag.property(name) != nil
+	for _, p := range tag.patterns {
+		if p.re.MatchString(name) {
+			matched = true
+			result.add(v.validateTag(s, data, propertyPath.schema("patternProperties", p.re.String()), nil, p.tag))
+		}
+	}
+	if matched || tag.additionalProperties == nil {
+		return
+	}
+	if tag.additionalProperties.falseSchema {
+		// reported on the map, at the path of the property
+		result.add(s.error(path.child(path.name, name), "additionalProperties", CodeAdditionalProperties, map[string]interface{}{"property": name}))
+		return
+	}
+	result.add(v.validateTag(s, data, propertyPath.schema("additionalProperties"), nil, tag.additionalProperties))
+}
+
+// validateContains checks the number of elements matching the contains tag.
+// The causes name the index of each element checked.
+func (v *Validator) validateContains(s *state, value reflect.Value, path evalPath, field *reflect.StructField, tag *tag) *ValidationError {
+	min, max := int64(1), int64(-1)
+	if tag.minContains != nil {
+		min = *tag.minContains
+	}
+	if tag.maxContains != nil {
+		max = *tag.maxContains
+	}
+	n := int64(0)
+	matched, unmatched := []*ValidationError{}, []*ValidationError{}
+	for i := 0; i < value.Len(); i++ {
+		itemPath := path.index(i)
+		ret, ok := v.match(s, value.Index(i), itemPath.schema("contains"), field, tag.contains)
+		if ok {
+			n++
+		}
+		if s.flag {
+			// flag walks only count the matches
+			continue
+		}
+		if !ok {
+			unmatched = append(unmatched, itemPath.error("contains", CodeContains, map[string]interface{}{"index": i}, ret))
+			continue
+		}
+		matched = append(matched, itemPath.error("contains", CodeContainsMatch, map[string]interface{}{"index": i}))
+	}
+	if n < min {
+		keyword := "contains"
+		if tag.minContains != nil {
+			keyword = "minContains"
+		}
+		return s.error(path, keyword, CodeMinContains, map[string]interface{}{"actual": n, "limit": min}, unmatched...)
+	}
+	if max >= 0 && n > max {
+		return s.error(path, "maxContains", CodeMaxContains, map[string]interface{}{"actual": n, "limit": max}, matched...)
+	}
+	return nil
+}
+
+func (v *Validator) validateString(s *state, str string, path evalPath, tag *tag) *ValidationError {
+	ret := s.result()
 	if tag != nil && (tag.minLength != nil || tag.maxLength != nil) {
 		l := int64(utf8.RuneCountInString(str))
 		if tag.minLength != nil && l < *tag.minLength {
-			ret.add(&ValidationError{
-				Message: fmt.Sprintf("String is too short (%d chars), minimum %d", l, *tag.minLength),
-				Name:    fieldName,
-			})
+			ret.add(s.error(path, "minLength", CodeMinLength, map[string]interface{}{"actual": l, "limit": *tag.minLength}))
 		}
 		if tag.maxLength != nil && l > *tag.maxLength {
-			ret.add(&ValidationError{
-				Message: fmt.Sprintf("String is too long (%d chars), maximum %d", l, *tag.maxLength),
-				Name:    fieldName,
-			})
+			ret.add(s.error(path, "maxLength", CodeMaxLength, map[string]interface{}{"actual": l, "limit": *tag.maxLength}))
 		}
 	}
 	if tag != nil && tag.pattern != nil && !tag.pattern.MatchString(str) {
-		ret.add(&ValidationError{
-			Message: fmt.Sprintf("String does not match pattern: %s", tag.pattern.String()),
-			Name:    fieldName,
-		})
-	}
-	if tag != nil && len(tag.enum) > 0 && !contains(tag.enum, str) {
-		ret.add(&ValidationError{
-			Message: fmt.Sprintf("No enum match for: %s", str),
-			Name:    fieldName,
-		})
+		ret.add(s.error(path, "pattern", CodePattern, map[string]interface{}{"pattern": tag.pattern.String()}))
 	}
 	return ret
 }
 
-func (v *Validator) validateNumber(num *big.Float, fieldName string, tag *tag) *ValidationError {
-	ret := newValidationError()
+func (v *Validator) validateNumber(s *state, num *big.Rat, path evalPath, tag *tag) *ValidationError {
+	ret := s.result()
 	if tag != nil && tag.minimum != nil {
-		if tag.exclusiveMinimumD4 != nil && *tag.exclusiveMinimumD4 && num.Cmp(tag.minimum) <= 0 {
-			ret.add(&ValidationError{
-				Message: fmt.Sprintf("Value %s is equal to exclusive minimum %s", num.String(), tag.minimum.String()),
-				Name:    fieldName,
-			})
-		}
-		if num.Cmp(tag.minimum) < 0 {
-			ret.add(&ValidationError{
-				Message: fmt.Sprintf("Value %s is less than minimum %s", num.String(), tag.minimum.String()),
-				Name:    fieldName,
-			})
-		}
-	}
-	if tag != nil && tag.exclusiveMinimumD6 != nil && num.Cmp(tag.exclusiveMinimumD6) <= 0 {
-		ret.add(&ValidationError{
-			Message: fmt.Sprintf("Value %s is equal to exclusive minimum %s", num.String(), tag.exclusiveMinimumD6.String()),
-			Name:    fieldName,
-		})
+		if tag.exclusiveMinimumD4 != nil && *tag.exclusiveMinimumD4 && compareNumber(num, tag.minimum) <= 0 {
+			ret.add(s.error(path, "exclusiveMinimum", CodeExclusiveMinimum, map[string]interface{}{"actual": jsonNumber(num), "limit": jsonNumber(tag.minimum)}))
+		}
+		if compareNumber(num, tag.minimum) < 0 {
+			ret.add(s.error(path, "minimum", CodeMinimum, map[string]interface{}{"actual": jsonNumber(num), "limit": jsonNumber(tag.minimum)}))
+		}
+	}
+	if tag != nil && tag.exclusiveMinimumD6 != nil && compareNumber(num, tag.exclusiveMinimumD6) <= 0 {
+		ret.add(s.error(path, "exclusiveMinimum", CodeExclusiveMinimum, map[string]interface{}{"actual": jsonNumber(num), "limit": jsonNumber(tag.exclusiveMinimumD6)}))
 	}
 	if tag != nil && tag.maximum != nil {
-		if tag.exclusiveMaximumD4 != nil && *tag.exclusiveMaximumD4 && num.Cmp(tag.maximum) >= 0 {
-			ret.add(&ValidationError{
-				Message: fmt.Sprintf("Value %s is equal to exclusive maximum %s", num.String(), tag.maximum.String()),
-				Name:    fieldName,
-			})
-		}
-		if num.Cmp(tag.maximum) > 0 {
-			ret.add(&ValidationError{
-				Message: fmt.Sprintf("Value %s is greater than maximum %s", num.String(), tag.maximum.String()),
-				Name:    fieldName,
-			})
-		}
-	}
-	if tag != nil && tag.exclusiveMaximumD6 != nil && num.Cmp(tag.exclusiveMaximumD6) >= 0 {
-		ret.add(&ValidationError{
-			Message: fmt.Sprintf("Value %s is equal to exclusive maximum %s", num.String(), tag.exclusiveMaximumD6.String()),
-			Name:    fieldName,
-		})
+		if tag.exclusiveMaximumD4 != nil && *tag.exclusiveMaximumD4 && compareNumber(num, tag.maximum) >= 0 {
+			ret.add(s.error(path, "exclusiveMaximum", CodeExclusiveMaximum, map[string]interface{}{"actual": jsonNumber(num), "limit": jsonNumber(tag.maximum)}))
+		}
+		if compareNumber(num, tag.maximum) > 0 {
+			ret.add(s.error(path, "maximum", CodeMaximum, map[string]interface{}{"actual": jsonNumber(num), "limit": jsonNumber(tag.maximum)}))
+		}
+	}
+	if tag != nil && tag.exclusiveMaximumD6 != nil && compareNumber(num, tag.exclusiveMaximumD6) >= 0 {
+		ret.add(s.error(path, "exclusiveMaximum", CodeExclusiveMaximum, map[string]interface{}{"actual": jsonNumber(num), "limit": jsonNumber(tag.exclusiveMaximumD6)}))
 	}
 	if tag != nil && tag.multipleOf != nil {
-		if m := new(big.Float).Quo(num, tag.multipleOf); !m.IsInt() {
-			ret.add(&ValidationError{
-				Message: fmt.Sprintf("Value %s is not a multiple of %s", num.String(), tag.multipleOf.String()),
-				Name:    fieldName,
-			})
+		if !s.multipleOf(num, tag.multipleOf) {
+			ret.add(s.error(path, "multipleOf", CodeMultipleOf, map[string]interface{}{"actual": jsonNumber(num), "multipleOf": jsonNumber(tag.multipleOf)}))
 		}
 	}
-	if tag != nil && len(tag.enum) > 0 {
-		if !contains(tag.enum, num.String()) {
-			ret.add(&ValidationError{
-				Message: fmt.Sprintf("No enum match for: %s", num.String()),
-				Name:    fieldName,
-			})
+	return ret
+}
+
+// compareNumber compares x and y like Cmp, without allocating when both are
+// integers.
+func compareNumber(x, y *big.Rat) int {
+	if x.IsInt() && y.IsInt() {
+		return x.Num().Cmp(y.Num())
+	}
+	return x.Cmp(y)
+}
+
+// multipleOf reports whether num is a multiple of m, without allocating
+// when both are integers.
+func (s *state) multipleOf(num, m *big.Rat) bool {
+	if num.IsInt() && m.IsInt() {
+		if x, y := num.Num(), m.Num(); x.IsInt64() && y.IsInt64() {
+			return x.Int64()%y.Int64() == 0
 		}
+		return s.rem.Rem(num.Num(), m.Num()).Sign() == 0
 	}
-	return ret
+	return new(big.Rat).Quo(num, m).IsInt()
 }
@@ -1,7 +1,15 @@
 package jsonschema
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"github.com/stretchr/testify/assert"
+	"math"
+	"math/big"
+	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -212,6 +220,93 @@ func TestValidator_Validate_Float_MultipleOf(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestValidator_Validate_Decimal(t *testing.T) {
+	type Sample struct {
+		Price float64 `jsonschema:"multipleOf:0.01,maximum:99.99"`
+		Ratio float32 `jsonschema:"multipleOf:0.1"`
+		ID    int64   `jsonschema:"maximum:9007199254740992"`
+		Seq   uint64  `jsonschema:"minimum:18446744073709551615"`
+	}
+
+	validator := NewValidator()
+
+	// valid
+	assert.NoError(t, validator.Validate(Sample{Price: 19.99, Ratio: 0.3, ID: 9007199254740992, Seq: math.MaxUint64}))
+
+	// invalid
+	err := validator.Validate(Sample{Price: 19.999, Ratio: 0.25, ID: 9007199254740993, Seq: math.MaxUint64 - 1})
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, ret.Causes, 4)
+	assert.Equal(t, "Value 19.999 is not a multiple of 0.01", ret.Causes[0].Causes[0].Message)
+	assert.Equal(t, "Value 0.25 is not a multiple of 0.1", ret.Causes[1].Causes[0].Message)
+	assert.Equal(t, "Value 9007199254740993 is greater than maximum 9007199254740992", ret.Causes[2].Causes[0].Message)
+	assert.Equal(t, "Value 18446744073709551614 is less than minimum 18446744073709551615", ret.Causes[3].Causes[0].Message)
+
+	// raw JSON is decoded exactly
+	type Raw struct {
+		ID json.RawMessage `jsonschema:"maximum:9007199254740992"`
+	}
+	assert.NoError(t, validator.Validate(Raw{ID: json.RawMessage(`9007199254740992`)}))
+	assert.Error(t, validator.Validate(Raw{ID: json.RawMessage(`9007199254740993`)}))
+
+	// ±Inf and NaN are not numbers of JSON
+	for text, f := range map[string]float64{"+Inf": math.Inf(1), "-Inf": math.Inf(-1), "NaN": math.NaN()} {
+		err = validator.Validate(Sample{Price: f, Ratio: float32(f), Seq: math.MaxUint64})
+		ret, ok = err.(*ValidationError)
+		assert.True(t, ok)
+		assert.Len(t, ret.Causes, 2)
+		assert.Equal(t, CodeInvalidNumber, ret.Causes[0].Code)
+		assert.Equal(t, "Invalid number: "+text, ret.Causes[0].Message)
+		assert.False(t, validator.IsValid(Sample{Price: f}))
+	}
+}
+
+func TestValidator_Validate_BigNumber(t *testing.T) {
+	type Sample struct {
+		Amount  json.Number `jsonschema:"minimum:0,multipleOf:0.01"`
+		Balance *big.Int    `jsonschema:"maximum:1000000000000000000000"`
+		Rate    *big.Float  `jsonschema:"exclusiveMinimum:0"`
+		Share   big.Rat     `jsonschema:"maximum:1"`
+		Total   interface{} `jsonschema:"type:integer"`
+	}
+
+	validator := NewValidator()
+	assert.NoError(t, validator.Register(Sample{}))
+
+	// valid
+	balance, _ := new(big.Int).SetString("1000000000000000000000", 10)
+	assert.NoError(t, validator.Validate(Sample{
+		Amount:  "19.99",
+		Balance: balance,
+		Rate:    big.NewFloat(0.5),
+		Share:   *big.NewRat(1, 3),
+		Total:   json.Number("10"),
+	}))
+
+	// invalid
+	balance = new(big.Int).Add(balance, big.NewInt(1))
+	err := validator.Validate(Sample{
+		Amount:  "-0.001",
+		Balance: balance,
+		Rate:    new(big.Float),
+		Share:   *big.NewRat(3, 2),
+		Total:   json.Number("10.5"),
+	})
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, ret.Causes, 5)
+	assert.Equal(t, "Value -0.001 is less than minimum 0", ret.Causes[0].Causes[0].Message)
+	assert.Equal(t, "Value -0.001 is not a multiple of 0.01", ret.Causes[0].Causes[1].Message)
+	assert.Equal(t, "Value 1000000000000000000001 is greater than maximum 1000000000000000000000", ret.Causes[1].Causes[0].Message)
+	assert.Equal(t, "Value 0 is equal to exclusive minimum 0", ret.Causes[2].Causes[0].Message)
+	assert.Equal(t, "Value 1.5 is greater than maximum 1", ret.Causes[3].Causes[0].Message)
+	assert.Equal(t, "Invalid type, expected integer but got number", ret.Causes[4].Causes[0].Message)
+
+	err = validator.Validate(Sample{Amount: "abc", Total: 1})
+	assert.Equal(t, "Invalid number: abc", err.Error())
+}
+
 func TestValidator_Validate_String_MaxLength(t *testing.T) {
 	type String struct {
 		Str string `jsonschema:"maxLength:5"`
@@ -561,3 +656,831 @@ func TestValidator_Validate_Map_PatternProperties(t *testing.T) {
 	err = validator.Validate(s)
 	assert.NoError(t, err)
 }
+
+func TestValidator_Validate_Concurrent(t *testing.T) {
+	type Sample struct {
+		Name string `jsonschema:"maxLength:3,pattern:^[a-z]+$"`
+		Num  int    `jsonschema:"minimum:1"`
+	}
+
+	validator := NewValidator()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				assert.NoError(t, validator.Validate(Sample{Name: "abc", Num: 1}))
+				assert.Error(t, validator.Validate(&Sample{Name: "abcd", Num: 1}))
+			}
+		}()
+	}
+	wg.Wait()
+
+	p1, err := validator.plan(reflect.TypeOf(Sample{}))
+	assert.NoError(t, err)
+	p2, err := validator.plan(reflect.TypeOf(Sample{}))
+	assert.NoError(t, err)
+	assert.True(t, p1 == p2)
+}
+
+func TestValidator_Validate_TagSyntaxError(t *testing.T) {
+	type UnknownKeyword struct {
+		Str string `jsonschema:"minLength:1,maxLenght:5"`
+	}
+	type InvalidPattern struct {
+		Str string `jsonschema:"pattern:[a-z"`
+	}
+	type Truncated struct {
+		Str string `jsonschema:"patt"`
+	}
+
+	validator := NewValidator()
+
+	err := validator.Validate(UnknownKeyword{})
+	assert.True(t, errors.Is(err, ErrTagSyntax))
+	tagErr, ok := err.(*TagError)
+	assert.True(t, ok)
+	assert.Equal(t, "jsonschema.UnknownKeyword", tagErr.Type)
+	assert.Equal(t, "Str", tagErr.Field)
+	assert.Equal(t, "maxLenght", tagErr.Keyword)
+	assert.Equal(t, 12, tagErr.Offset)
+	assert.Equal(t, `tag syntax error in jsonschema.UnknownKeyword.Str: "maxLenght" at offset 12: unknown keyword`, err.Error())
+
+	err = validator.Validate(InvalidPattern{})
+	assert.True(t, errors.Is(err, ErrTagSyntax))
+	tagErr, ok = err.(*TagError)
+	assert.True(t, ok)
+	assert.Equal(t, "pattern", tagErr.Keyword)
+	assert.Equal(t, 8, tagErr.Offset)
+
+	err = validator.Validate(Truncated{})
+	assert.True(t, errors.Is(err, ErrTagSyntax))
+	tagErr, ok = err.(*TagError)
+	assert.True(t, ok)
+	assert.Equal(t, "patt", tagErr.Keyword)
+	assert.Equal(t, "unknown keyword", tagErr.Reason)
+
+	// nested structs, also inside slices
+	type Outer struct {
+		Name  string `jsonschema:"minLength:1"`
+		Inner UnknownKeyword
+	}
+	type OuterSlice struct {
+		Inner []UnknownKeyword
+	}
+	err = validator.Validate(Outer{Inner: UnknownKeyword{Str: "abcdefg"}})
+	tagErr, ok = err.(*TagError)
+	assert.True(t, ok)
+	assert.Equal(t, "maxLenght", tagErr.Keyword)
+	err = validator.Validate(OuterSlice{Inner: []UnknownKeyword{{Str: "a"}}})
+	assert.True(t, errors.Is(err, ErrTagSyntax))
+	assert.False(t, validator.IsValid(Outer{Name: "a", Inner: UnknownKeyword{Str: "a"}}))
+	assert.False(t, validator.IsValid(OuterSlice{Inner: []UnknownKeyword{{Str: "a"}}}))
+}
+
+func TestValidator_Register(t *testing.T) {
+	type Child struct {
+		Code string `jsonschema:"format:no-such-format"`
+	}
+	type Sample struct {
+		Num      int               `jsonschema:"minLength:1"`
+		Range    float64           `jsonschema:"minimum:10,maximum:5"`
+		Str      string            `jsonschema:"pattern:[a-z"`
+		Strs     []string          `jsonschema:"minItems:1,maxLength:3"`
+		Children map[string]*Child `jsonschema:"minProperties:1"`
+		Counts   map[int]int       `jsonschema:"required:[a]"`
+	}
+	type Valid struct {
+		Str string `jsonschema:"format:email,maxLength:10"`
+	}
+
+	validator := NewValidator()
+
+	err := validator.Register(Sample{})
+	assert.True(t, errors.Is(err, ErrTagSyntax))
+	errs, ok := err.(TagErrors)
+	assert.True(t, ok)
+	assert.Len(t, errs, 5)
+	assert.Equal(t, `tag syntax error in jsonschema.Sample.Num: "minLength" at offset 0: keyword does not apply to int`, errs[0].Error())
+	assert.Equal(t, `tag syntax error in jsonschema.Sample.Range: "minimum" at offset 0: minimum 10 is greater than maximum 5`, errs[1].Error())
+	assert.Equal(t, "pattern", errs[2].Keyword)
+	assert.Equal(t, "jsonschema.Child", errs[3].Type)
+	assert.Equal(t, `unknown format "no-such-format"`, errs[3].Reason)
+	assert.Equal(t, `tag syntax error in jsonschema.Sample.Counts: "required" at offset 0: keys of map[int]int are not strings`, errs[4].Error())
+
+	// Validate does not check the tags, maps without string keys miss every
+	// required property
+	type Key string
+	type Maps struct {
+		Counts map[int]int `jsonschema:"required:[a]"`
+		Named  map[Key]int `jsonschema:"required:[a]"`
+	}
+	err = validator.Validate(Maps{Counts: map[int]int{1: 1}, Named: map[Key]int{"a": 1}})
+	assert.Equal(t, "Missing required property: [a]", err.Error())
+
+	assert.NoError(t, validator.Register(Valid{}, &Valid{}))
+	assert.Panics(t, func() { validator.MustRegister(&Sample{}) })
+	assert.NotPanics(t, func() { validator.MustRegister(Valid{}) })
+}
+
+func TestValidator_Validate_Draft(t *testing.T) {
+	type D4 struct {
+		Num int `jsonschema:"minimum:5,exclusiveMinimum:true"`
+	}
+	type D6 struct {
+		Num int `jsonschema:"exclusiveMinimum:5"`
+	}
+
+	draft04 := NewValidator(WithDraft(Draft04))
+	assert.Error(t, draft04.Validate(D4{Num: 5}))
+	assert.NoError(t, draft04.Validate(D4{Num: 6}))
+	err := draft04.Validate(D6{Num: 6})
+	assert.True(t, errors.Is(err, ErrTagSyntax))
+	assert.Equal(t, `tag syntax error in jsonschema.D6.Num: "exclusiveMinimum" at offset 0: must be a boolean in draft-04`, err.Error())
+
+	draft202012 := NewValidator(WithDraft(Draft202012))
+	assert.Error(t, draft202012.Validate(D6{Num: 5}))
+	assert.NoError(t, draft202012.Validate(D6{Num: 6}))
+	err = draft202012.Validate(D4{Num: 6})
+	assert.True(t, errors.Is(err, ErrTagSyntax))
+	assert.Equal(t, `tag syntax error in jsonschema.D4.Num: "exclusiveMinimum" at offset 10: must be a number in draft 2020-12`, err.Error())
+}
+
+func TestValidator_Validate_Combinators(t *testing.T) {
+	type Sample struct {
+		Host   string `jsonschema:"anyOf((format:ipv4),(format:hostname,maxLength:10))"`
+		Code   string `jsonschema:"oneOf((pattern:^a),(pattern:b$))"`
+		Num    int    `jsonschema:"allOf((minimum:1),(multipleOf:2))"`
+		Reject string `jsonschema:"not(enum:[root,admin])"`
+	}
+
+	validator := NewValidator()
+
+	// valid
+	assert.NoError(t, validator.Validate(Sample{Host: "192.168.1.1", Code: "ax", Num: 2, Reject: "user"}))
+	assert.NoError(t, validator.Validate(Sample{Host: "example", Code: "xb", Num: 4, Reject: "guest"}))
+
+	// invalid
+	err := validator.Validate(Sample{Host: "a.example.com", Code: "ab", Num: 3, Reject: "root"})
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, ret.Causes, 4)
+
+	host := ret.Causes[0].Causes[0]
+	assert.Equal(t, "Host", host.Name)
+	assert.Equal(t, "Does not match any schema of anyOf", host.Message)
+	assert.Len(t, host.Causes, 2)
+	assert.Equal(t, "Format validation failed ()", host.Causes[0].Causes[0].Message)
+	assert.Equal(t, "String is too long (13 chars), maximum 10", host.Causes[1].Causes[0].Message)
+
+	assert.Equal(t, "Matches more than one schema of oneOf (indices 0 and 1)", ret.Causes[1].Causes[0].Message)
+	assert.Equal(t, "Value 3 is not a multiple of 2", ret.Causes[2].Causes[0].Message)
+	assert.Equal(t, "Matches the schema of not", ret.Causes[3].Causes[0].Message)
+
+	type Invalid struct {
+		Str string `jsonschema:"anyOf((maxLength:1),(maxLenght:2))"`
+	}
+	err = validator.Validate(Invalid{})
+	tagErr, ok := err.(*TagError)
+	assert.True(t, ok)
+	assert.Equal(t, "maxLenght", tagErr.Keyword)
+	assert.Equal(t, 21, tagErr.Offset)
+}
+
+func TestValidator_Validate_IfThenElse(t *testing.T) {
+	type Address struct {
+		_          struct{} `jsonschema:"if(properties(country(enum:[US]))),then(properties(postalCode(pattern:^[0-9]{5}$))),else(properties(postalCode(minLength:3)))"`
+		Country    string   `json:"country"`
+		PostalCode string   `json:"postalCode" jsonschema:"maxLength:10"`
+	}
+
+	validator := NewValidator()
+
+	// valid
+	assert.NoError(t, validator.Register(Address{}))
+	assert.NoError(t, validator.Validate(Address{Country: "US", PostalCode: "94105"}))
+	assert.NoError(t, validator.Validate(Address{Country: "JP", PostalCode: "100-0001"}))
+
+	// invalid
+	err := validator.Validate(Address{Country: "US", PostalCode: "SW1A"})
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, ret.Causes, 1)
+	then := ret.Causes[0].Causes[0]
+	assert.Equal(t, "Does not match the schema of then", then.Message)
+	postalCode := then.Causes[0].Causes[0].Causes[0]
+	assert.Equal(t, "postalCode", postalCode.Name)
+	assert.Equal(t, "String does not match pattern: ^[0-9]{5}$", postalCode.Message)
+
+	err = validator.Validate(Address{Country: "JP", PostalCode: "1"})
+	ret, ok = err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, "Does not match the schema of else", ret.Causes[0].Causes[0].Message)
+
+	type Unknown struct {
+		_    struct{} `jsonschema:"if(properties(nme(minLength:1))),then(properties(name(maxLength:2)))"`
+		Name string   `json:"name"`
+	}
+	err = validator.Register(Unknown{})
+	assert.EqualError(t, err, `tag syntax error in jsonschema.Unknown._: "properties" at offset 3: unknown property "nme" of jsonschema.Unknown`)
+}
+
+func TestValidator_Validate_Array_Contains(t *testing.T) {
+	type Sample struct {
+		Roles  []string `jsonschema:"contains(pattern:^admin)"`
+		Scores []int    `jsonschema:"contains(minimum:100),minContains:2,maxContains:3"`
+	}
+
+	validator := NewValidator()
+
+	// valid
+	assert.NoError(t, validator.Validate(Sample{Roles: []string{"user", "admin:write"}, Scores: []int{100, 1, 200}}))
+
+	// invalid
+	err := validator.Validate(Sample{Roles: []string{"user", "guest"}, Scores: []int{100, 100, 100, 100}})
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, ret.Causes, 2)
+
+	roles := ret.Causes[0].Causes[0]
+	assert.Equal(t, "Roles", roles.Name)
+	assert.Equal(t, "Array contains too few matching items (0), minimum 1", roles.Message)
+	assert.Len(t, roles.Causes, 2)
+	assert.Equal(t, "Roles[1]", roles.Causes[1].Name)
+	assert.Equal(t, "Item 1 does not match the schema of contains", roles.Causes[1].Message)
+
+	scores := ret.Causes[1].Causes[0]
+	assert.Equal(t, "Array contains too many matching items (4), maximum 3", scores.Message)
+	assert.Len(t, scores.Causes, 4)
+	assert.Equal(t, "Scores[3]", scores.Causes[3].Name)
+
+	type Invalid struct {
+		Scores []int `jsonschema:"contains(minLength:1),minContains:3,maxContains:2"`
+	}
+	errs, ok := validator.Register(Invalid{}).(TagErrors)
+	assert.True(t, ok)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "keyword does not apply to int", errs[0].Reason)
+	assert.Equal(t, "minContains 3 is greater than maxContains 2", errs[1].Reason)
+}
+
+func TestValidator_Validate_ItemsKeysValues(t *testing.T) {
+	type Sample struct {
+		Matrix [][]int           `jsonschema:"maxItems:2,items(minItems:1,items(minimum:0))"`
+		Labels map[string]string `jsonschema:"keys(pattern:^[a-z]+$),values(maxLength:3)"`
+	}
+
+	validator := NewValidator()
+
+	// valid
+	assert.NoError(t, validator.Register(Sample{}))
+	assert.NoError(t, validator.Validate(Sample{Matrix: [][]int{{0, 1}, {2}}, Labels: map[string]string{"env": "dev"}}))
+
+	// invalid
+	err := validator.Validate(Sample{Matrix: [][]int{{}, {-1}}, Labels: map[string]string{"Env": "production"}})
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, ret.Causes, 2)
+
+	matrix := ret.Causes[0].Causes
+	assert.Len(t, matrix, 2)
+	assert.Equal(t, "Matrix[0]", matrix[0].Causes[0].Name)
+	assert.Equal(t, "Array is too short (0), minimum 1", matrix[0].Causes[0].Message)
+	assert.Equal(t, "Matrix[1][0]", matrix[1].Causes[0].Causes[0].Name)
+	assert.Equal(t, "Value -1 is less than minimum 0", matrix[1].Causes[0].Causes[0].Message)
+
+	labels := ret.Causes[1].Causes
+	assert.Len(t, labels, 2)
+	assert.Equal(t, "Labels[Env](key)", labels[0].Causes[0].Name)
+	assert.Equal(t, "String does not match pattern: ^[a-z]+$", labels[0].Causes[0].Message)
+	assert.Equal(t, "Labels[Env](value)", labels[1].Causes[0].Name)
+	assert.Equal(t, "String is too long (10 chars), maximum 3", labels[1].Causes[0].Message)
+
+	type Invalid struct {
+		Names []string `jsonschema:"maxLength:5,items(minLength:1)"`
+	}
+	err = validator.Register(Invalid{})
+	assert.EqualError(t, err, `tag syntax error in jsonschema.Invalid.Names: "maxLength" at offset 0: keyword does not apply to []string`)
+}
+
+func TestValidator_Validate_Array_PrefixItems(t *testing.T) {
+	type Sample struct {
+		Point  [2]float64    `jsonschema:"prefixItems((minimum:-90,maximum:90),(minimum:-180,maximum:180))"`
+		Record []interface{} `jsonschema:"prefixItems((maxLength:3),(minimum:0)),items:false"`
+	}
+
+	validator := NewValidator()
+
+	// valid
+	assert.NoError(t, validator.Register(Sample{}))
+	assert.NoError(t, validator.Validate(Sample{Point: [2]float64{35.6, 139.7}, Record: []interface{}{"abc", 1}}))
+
+	// invalid
+	err := validator.Validate(Sample{Point: [2]float64{35.6, 200}, Record: []interface{}{"abcd", 1, true}})
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, ret.Causes, 2)
+
+	point := ret.Causes[0].Causes[0].Causes[0]
+	assert.Equal(t, "Point[1]", point.Name)
+	assert.Equal(t, "Value 200 is greater than maximum 180", point.Message)
+
+	record := ret.Causes[1].Causes
+	assert.Len(t, record, 2)
+	assert.Equal(t, "Record[0]", record[0].Causes[0].Name)
+	assert.Equal(t, "Record[2]", record[1].Name)
+	assert.Equal(t, "Additional item 2 is not allowed", record[1].Message)
+}
+
+func TestValidator_Validate_Map_AdditionalProperties(t *testing.T) {
+	type Sample struct {
+		Attributes map[string]interface{} `jsonschema:"properties(name(maxLength:5)),patternProperties((^x-)(minLength:1)),additionalProperties:false"`
+		Payment    map[string]string      `jsonschema:"propertyNames(maxLength:14),dependentRequired(creditCard:[billingAddress,cvv])"`
+	}
+
+	validator := NewValidator()
+
+	// valid
+	assert.NoError(t, validator.Register(Sample{}))
+	assert.NoError(t, validator.Validate(Sample{
+		Attributes: map[string]interface{}{"name": "test", "x-id": "1"},
+		Payment:    map[string]string{"creditCard": "4242", "billingAddress": "Tokyo", "cvv": "123"},
+	}))
+
+	// invalid
+	err := validator.Validate(Sample{
+		Attributes: map[string]interface{}{"name": "test", "x-id": "", "color": "red"},
+		Payment:    map[string]string{"creditCard": "4242", "cvv": "123", "cardVerificationCode": "1"},
+	})
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, ret.Causes, 2)
+
+	attributes := ret.Causes[0].Causes
+	assert.Len(t, attributes, 2)
+	assert.Equal(t, "Attributes", attributes[0].Name)
+	assert.Equal(t, "Additional property color is not allowed", attributes[0].Message)
+	assert.Equal(t, "Attributes.x-id", attributes[1].Causes[0].Name)
+	assert.Equal(t, "String is too short (0 chars), minimum 1", attributes[1].Causes[0].Message)
+
+	payment := ret.Causes[1].Causes
+	assert.Len(t, payment, 2)
+	assert.Equal(t, "Missing property billingAddress required by creditCard", payment[0].Message)
+	assert.Equal(t, "Payment[cardVerificationCode](key)", payment[1].Causes[0].Name)
+	assert.Equal(t, "String is too long (20 chars), maximum 14", payment[1].Causes[0].Message)
+}
+
+func TestValidator_Validate_Required(t *testing.T) {
+	type Child struct {
+		Name string `json:"name"`
+	}
+	type Sample struct {
+		_        struct{}          `jsonschema:"if(properties(kind(enum:[child]))),then(required:[child])"`
+		Kind     string            `json:"kind"`
+		ID       *string           `json:"id" jsonschema:"required"`
+		Tags     []string          `json:"tags" jsonschema:"required"`
+		Note     *string           `json:"note" jsonschema:"required,nullable"`
+		Nickname string            `json:"nickname,omitempty" jsonschema:"required"`
+		Labels   map[string]string `json:"labels,omitempty" jsonschema:"required"`
+		Child    *Child            `json:"child,omitempty"`
+		Count    int               `json:"count" jsonschema:"required"`
+	}
+
+	id := "1"
+	valid := Sample{ID: &id, Tags: []string{}, Nickname: "n", Labels: map[string]string{"a": "b"}}
+
+	validator := NewValidator()
+
+	// valid
+	assert.NoError(t, validator.Register(Sample{}))
+	assert.NoError(t, validator.Validate(valid))
+
+	// invalid
+	err := validator.Validate(Sample{Kind: "child"})
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, ret.Causes, 5)
+	assert.Equal(t, "ID", ret.Causes[0].Name)
+	assert.Equal(t, "Required field must not be null", ret.Causes[0].Message)
+	assert.Equal(t, "Tags", ret.Causes[1].Name)
+	assert.Equal(t, "Nickname", ret.Causes[2].Name)
+	assert.Equal(t, "Missing required field", ret.Causes[2].Message)
+	assert.Equal(t, "Labels", ret.Causes[3].Name)
+	then := ret.Causes[4].Causes[0]
+	assert.Equal(t, "Does not match the schema of then", then.Message)
+	assert.Equal(t, "child", then.Causes[0].Causes[0].Name)
+	assert.Equal(t, "Missing required field", then.Causes[0].Causes[0].Message)
+
+	// modes
+	err = NewValidator(WithRequiredMode(RequiredNullable)).Validate(Sample{Nickname: "n", Labels: map[string]string{}})
+	assert.Equal(t, "Missing required field", err.Error())
+	err = NewValidator(WithRequiredMode(RequiredNonZero)).Validate(valid)
+	assert.Equal(t, "Required field must not be zero", err.Error())
+
+	type Invalid struct {
+		_    struct{} `jsonschema:"required:[nmae]"`
+		Name string   `json:"name"`
+	}
+	err = validator.Register(Invalid{})
+	assert.EqualError(t, err, `tag syntax error in jsonschema.Invalid._: "required" at offset 0: unknown property "nmae" of jsonschema.Invalid`)
+}
+
+func TestValidator_Validate_Type(t *testing.T) {
+	type Sample struct {
+		Metadata interface{}     `jsonschema:"type:[object,null],maxProperties:2"`
+		Count    interface{}     `jsonschema:"type:integer"`
+		Raw      json.RawMessage `jsonschema:"type:array,maxItems:2"`
+	}
+
+	validator := NewValidator()
+
+	// valid
+	assert.NoError(t, validator.Register(Sample{}))
+	assert.NoError(t, validator.Validate(Sample{Count: 1.0, Raw: json.RawMessage(`[1,2]`)}))
+	assert.NoError(t, validator.Validate(Sample{Metadata: map[string]interface{}{"a": 1}, Count: int64(2), Raw: json.RawMessage(`[]`)}))
+
+	// invalid
+	err := validator.Validate(Sample{Metadata: "meta", Count: 1.5, Raw: json.RawMessage(`[1,2,3]`)})
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, ret.Causes, 3)
+	assert.Equal(t, "Metadata", ret.Causes[0].Causes[0].Name)
+	assert.Equal(t, "Invalid type, expected object or null but got string", ret.Causes[0].Causes[0].Message)
+	assert.Equal(t, "Invalid type, expected integer but got number", ret.Causes[1].Causes[0].Message)
+	assert.Equal(t, "Array is too long (3), maximum 2", ret.Causes[2].Causes[0].Message)
+
+	err = validator.Validate(Sample{Count: 1, Raw: json.RawMessage(`{"a":1}`)})
+	assert.Equal(t, "Invalid type, expected array but got object", err.Error())
+
+	type Invalid struct {
+		Name string `jsonschema:"type:integer"`
+		Kind string `jsonschema:"type:strnig"`
+	}
+	errs, ok := validator.Register(Invalid{}).(TagErrors)
+	assert.True(t, ok)
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "type [integer] never matches string", errs[0].Reason)
+	assert.Equal(t, `unknown type "strnig"`, errs[1].Reason)
+}
+
+func TestValidator_Validate_EnumConst(t *testing.T) {
+	type Sample struct {
+		Flag    bool        `jsonschema:"const:true"`
+		Rate    float64     `jsonschema:"enum:[0.5,1,2]"`
+		Code    string      `jsonschema:"enum:[\"1\",a]"`
+		Feature interface{} `jsonschema:"enum:[\"on\",1,true,null]"`
+		Point   []int       `jsonschema:"items(minimum:0),enum:[[0,0],[1,1]]"`
+	}
+
+	validator := NewValidator()
+
+	// valid
+	assert.NoError(t, validator.Validate(Sample{Flag: true, Rate: 1.0, Code: "1", Feature: nil, Point: []int{1, 1}}))
+	assert.NoError(t, validator.Validate(Sample{Flag: true, Rate: 0.5, Code: "a", Feature: 1.0, Point: []int{0, 0}}))
+
+	// invalid
+	err := validator.Validate(Sample{Flag: false, Rate: 1.5, Code: "b", Feature: "off", Point: []int{0, 1}})
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, ret.Causes, 5)
+	assert.Equal(t, "Value false does not match const true", ret.Causes[0].Causes[0].Message)
+	assert.Equal(t, "No enum match for: 1.5", ret.Causes[1].Causes[0].Message)
+	assert.Equal(t, "No enum match for: b", ret.Causes[2].Causes[0].Message)
+	assert.Equal(t, "No enum match for: off", ret.Causes[3].Causes[0].Message)
+	assert.Equal(t, "No enum match for: [0,1]", ret.Causes[4].Causes[0].Message)
+
+	err = validator.Validate(Sample{Flag: true, Rate: 1, Code: "a", Feature: false, Point: []int{1, 1}})
+	assert.Equal(t, "No enum match for: false", err.Error())
+}
+
+func TestValidator_Validate_QuotedValues(t *testing.T) {
+	type Sample struct {
+		Code  string   `jsonschema:"pattern:^[a-z]{1,3}$,maxLength:3"`
+		Name  string   `jsonschema:"pattern:'^[^()]{1,4}$',enum:['a, b','it\\'s',\"x ]\"]"`
+		Tags  []string `jsonschema:"items(pattern:\"^(a|b){2,}$\")"`
+		Const string   `jsonschema:"const:'1,2'"`
+		Point []int    `jsonschema:"items(minimum:0),const:[1, 2]"`
+	}
+
+	validator := NewValidator()
+
+	// valid
+	assert.NoError(t, validator.Register(Sample{}))
+	assert.NoError(t, validator.Validate(Sample{Code: "abc", Name: "a, b", Tags: []string{"abab"}, Const: "1,2", Point: []int{1, 2}}))
+	assert.NoError(t, validator.Validate(Sample{Code: "a", Name: "it's", Tags: []string{}, Const: "1,2", Point: []int{1, 2}}))
+
+	// invalid
+	err := validator.Validate(Sample{Code: "abcd", Name: "x", Tags: []string{"ac"}, Const: "1", Point: []int{2, 1}})
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, ret.Causes, 5)
+	assert.Equal(t, "String does not match pattern: ^[a-z]{1,3}$", ret.Causes[0].Causes[1].Message)
+	assert.Equal(t, "No enum match for: x", ret.Causes[1].Causes[0].Message)
+	assert.Equal(t, "String does not match pattern: ^(a|b){2,}$", ret.Causes[2].Causes[0].Causes[0].Message)
+	assert.Equal(t, "Value 1 does not match const 1,2", ret.Causes[3].Causes[0].Message)
+	assert.Equal(t, "Value [2,1] does not match const [1,2]", ret.Causes[4].Causes[0].Message)
+
+	type Invalid struct {
+		Name string `jsonschema:"pattern:'abc,maxLength:3"`
+	}
+	err = validator.Validate(Invalid{})
+	assert.EqualError(t, err, `tag syntax error in jsonschema.Invalid.Name: "pattern" at offset 8: unterminated string`)
+}
+
+func TestValidator_Validate_Bool(t *testing.T) {
+	type Sample struct {
+		Consent  bool            `jsonschema:"const:true"`
+		Terms    *bool           `jsonschema:"required,enum:[true]"`
+		Opt      bool            `jsonschema:"format:opt-in"`
+		Features map[bool]string `jsonschema:"keys(enum:[true])"`
+	}
+
+	validator := NewValidator()
+	validator.AddFormat("opt-in", func(value *reflect.Value, field *reflect.StructField) error {
+		if value.Kind() == reflect.Bool && !value.Bool() {
+			return errors.New("opt-in required")
+		}
+		return nil
+	})
+
+	yes, no := true, false
+
+	// valid
+	assert.NoError(t, validator.Register(Sample{}))
+	assert.NoError(t, validator.Validate(Sample{Consent: true, Terms: &yes, Opt: true, Features: map[bool]string{true: "on"}}))
+
+	// invalid
+	err := validator.Validate(Sample{Consent: false, Terms: &no, Opt: false, Features: map[bool]string{false: "off"}})
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, ret.Causes, 4)
+	assert.Equal(t, "Value false does not match const true", ret.Causes[0].Causes[0].Message)
+	assert.Equal(t, "No enum match for: false", ret.Causes[1].Causes[0].Message)
+	assert.Equal(t, "Format validation failed (opt-in required)", ret.Causes[2].Causes[0].Message)
+	assert.Equal(t, "Features[false](key)", ret.Causes[3].Causes[0].Causes[0].Name)
+
+	err = validator.Validate(Sample{Consent: true, Opt: true})
+	assert.Equal(t, "Required field must not be null", err.Error())
+}
+
+func TestValidator_Validate_Path(t *testing.T) {
+	type Address struct {
+		Street string `json:"street" jsonschema:"minLength:1"`
+	}
+	type Sample struct {
+		ID      *string           `json:"id" jsonschema:"required"`
+		Address Address           `json:"address"`
+		Tags    []string          `json:"tags" jsonschema:"maxLength:3"`
+		Labels  map[string]string `json:"labels" jsonschema:"values(maxLength:3)"`
+		Host    string            `json:"host" jsonschema:"anyOf((format:ipv4),(maxLength:3))"`
+	}
+
+	err := NewValidator().Validate(Sample{
+		Tags:   []string{"ok", "a/b~c"},
+		Labels: map[string]string{"a/b": "long"},
+		Host:   "example",
+	})
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, ret.Causes, 5)
+
+	id := ret.Causes[0]
+	assert.Equal(t, "ID", id.Name)
+	assert.Equal(t, "/id", id.InstancePath)
+	assert.Equal(t, "/required", id.KeywordLocation)
+	assert.Equal(t, "required", id.Keyword)
+
+	street := ret.Causes[1].Causes[0].Causes[0]
+	assert.Equal(t, "Address.Street", street.Name)
+	assert.Equal(t, "/address/street", street.InstancePath)
+	assert.Equal(t, "/properties/address/properties/street/minLength", street.KeywordLocation)
+	assert.Equal(t, "minLength", street.Keyword)
+
+	tag := ret.Causes[2].Causes[0].Causes[0]
+	assert.Equal(t, "Tags[1]", tag.Name)
+	assert.Equal(t, "/tags/1", tag.InstancePath)
+	assert.Equal(t, "/properties/tags/items/maxLength", tag.KeywordLocation)
+
+	label := ret.Causes[3].Causes[0].Causes[0]
+	assert.Equal(t, "/labels/a~1b", label.InstancePath)
+	assert.Equal(t, "/properties/labels/additionalProperties/maxLength", label.KeywordLocation)
+
+	host := ret.Causes[4].Causes[0]
+	assert.Equal(t, "/host", host.InstancePath)
+	assert.Equal(t, "/properties/host/anyOf", host.KeywordLocation)
+	assert.Equal(t, "/properties/host/anyOf/0/format", host.Causes[0].Causes[0].KeywordLocation)
+	assert.Equal(t, "/properties/host/anyOf/1/maxLength", host.Causes[1].Causes[0].KeywordLocation)
+}
+
+func TestValidator_Validate_Code(t *testing.T) {
+	type Sample struct {
+		Name  string   `jsonschema:"minLength:3,pattern:^[a-z]+$"`
+		Price float64  `jsonschema:"maximum:9.99"`
+		Tags  []string `jsonschema:"maxItems:1"`
+		Kind  string   `jsonschema:"enum:[a,b]"`
+		Note  *string  `jsonschema:"required"`
+	}
+
+	err := NewValidator().Validate(Sample{Name: "A", Price: 10.5, Tags: []string{"x", "y"}, Kind: "c"})
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Len(t, ret.Causes, 5)
+
+	name := ret.Causes[0].Causes
+	assert.Equal(t, CodeMinLength, name[0].Code)
+	assert.Equal(t, map[string]interface{}{"actual": int64(1), "limit": int64(3)}, name[0].Params)
+	assert.Equal(t, CodePattern, name[1].Code)
+	assert.Equal(t, map[string]interface{}{"pattern": "^[a-z]+$"}, name[1].Params)
+
+	price := ret.Causes[1].Causes[0]
+	assert.Equal(t, CodeMaximum, price.Code)
+	assert.Equal(t, map[string]interface{}{"actual": json.Number("10.5"), "limit": json.Number("9.99")}, price.Params)
+
+	tags := ret.Causes[2].Causes[0]
+	assert.Equal(t, CodeMaxItems, tags.Code)
+	assert.Equal(t, map[string]interface{}{"actual": int64(2), "limit": int64(1)}, tags.Params)
+
+	kind := ret.Causes[3].Causes[0]
+	assert.Equal(t, CodeEnum, kind.Code)
+	assert.Equal(t, map[string]interface{}{"actual": "c", "enum": []interface{}{"a", "b"}}, kind.Params)
+
+	assert.Equal(t, CodeNotNull, ret.Causes[4].Code)
+	assert.Nil(t, ret.Causes[4].Params)
+}
+
+func TestValidator_Validate_Locale(t *testing.T) {
+	type Sample struct {
+		Name string `jsonschema:"minLength:3"`
+		Code string `jsonschema:"format:code"`
+	}
+	sample := Sample{Name: "a", Code: "x"}
+
+	newValidator := func(opts ...Option) *Validator {
+		validator := NewValidator(opts...)
+		validator.AddFormat("code", func(value *reflect.Value, field *reflect.StructField) error {
+			return errors.New("bad code")
+		})
+		return validator
+	}
+
+	// default
+	err := newValidator().Validate(sample)
+	assert.Equal(t, "String is too short (1 chars), minimum 3; Format validation failed (bad code)", err.Error())
+
+	// built-in catalog
+	err = newValidator(WithLocale("ja")).Validate(sample)
+	assert.Equal(t, "文字列が短すぎます (1文字)。最小3文字です; フォーマットの検証に失敗しました (bad code)", err.Error())
+
+	// per call, falling back to English
+	validator := newValidator(WithLocale("ja"))
+	err = validator.ValidateContext(ContextWithLocale(context.Background(), "fr"), sample)
+	assert.Equal(t, "String is too short (1 chars), minimum 3; Format validation failed (bad code)", err.Error())
+
+	// custom messages
+	assert.NoError(t, validator.AddMessages("ja", map[string]string{
+		CodeMinLength: "{{.limit}}文字以上で入力してください",
+		"format:code": "コードが不正です",
+	}))
+	err = validator.Validate(sample)
+	assert.Equal(t, "3文字以上で入力してください; コードが不正です", err.Error())
+	ret := err.(*ValidationError)
+	assert.Equal(t, CodeFormat, ret.Causes[1].Causes[0].Code)
+
+	assert.Error(t, validator.AddMessages("ja", map[string]string{CodeMaxLength: "{{.limit"}))
+
+	// messages printing params alone render as their templates do
+	params := map[string]interface{}{
+		"actual": 1, "limit": big.NewRat(3, 2), "pattern": "^a", "error": errors.New("bad"),
+		"index": 2, "property": "name", "dependent": "id", "multipleOf": json.Number("0.5"),
+	}
+	for locale, catalog := range catalogs {
+		for code, m := range catalog {
+			if m.parts == nil {
+				continue
+			}
+			buf := &bytes.Buffer{}
+			assert.NoError(t, m.t.Execute(buf, params))
+			assert.Equal(t, buf.String(), render(m, code, params), locale+" "+code)
+		}
+	}
+}
+
+func TestValidator_Validate_MaxErrors(t *testing.T) {
+	type Sample struct {
+		Name  string `jsonschema:"minLength:3,pattern:^[a-z]+$"`
+		Host  string `jsonschema:"anyOf((format:ipv4),(maxLength:5))"`
+		Items []int  `jsonschema:"items(maximum:1)"`
+	}
+	sample := Sample{Name: "A", Host: "local", Items: make([]int, 100000)}
+	for i := range sample.Items {
+		sample.Items[i] = i
+	}
+
+	err := NewValidator(WithFailFast()).Validate(sample)
+	assert.Equal(t, "String is too short (1 chars), minimum 3", err.Error())
+
+	// the failing branch of anyOf is not an error of the value
+	sample.Name = "abc"
+	err = NewValidator(WithFailFast()).Validate(sample)
+	assert.Equal(t, "Value 2 is greater than maximum 1", err.Error())
+	assert.Equal(t, "/Items/2", err.(*ValidationError).Format(OutputBasic).Errors[0].InstanceLocation)
+
+	err = NewValidator(WithMaxErrors(3)).Validate(sample)
+	assert.Len(t, err.(*ValidationError).Format(OutputBasic).Errors, 3)
+
+	err = NewValidator().Validate(sample)
+	assert.Len(t, err.(*ValidationError).Format(OutputBasic).Errors, 99998)
+}
+
+func TestValidator_IsValid(t *testing.T) {
+	type Address struct {
+		City string `jsonschema:"required,minLength:1"`
+	}
+	type Sample struct {
+		Name    string         `jsonschema:"minLength:3,pattern:^[a-z]+$"`
+		Age     int            `jsonschema:"minimum:0,maximum:150,multipleOf:1"`
+		Admin   bool           `jsonschema:"type:boolean"`
+		Tags    []string       `jsonschema:"maxItems:3,items(maxLength:5)"`
+		Code    string         `jsonschema:"anyOf((maxLength:1),(pattern:^a))"`
+		Counts  map[string]int `jsonschema:"values(minimum:1)"`
+		Address Address
+	}
+	sample := &Sample{Name: "abc", Age: 42, Tags: []string{"x", "y"}, Code: "a", Counts: map[string]int{"a": 1, "b": 2}, Address: Address{City: "Tokyo"}}
+	validator := NewValidator()
+
+	assert.True(t, validator.IsValid(sample))
+	if !raceEnabled {
+		assert.Equal(t, 0.0, testing.AllocsPerRun(100, func() {
+			validator.IsValid(sample)
+		}))
+	}
+
+	invalid := *sample
+	invalid.Code = "abc"
+	assert.True(t, validator.IsValid(invalid))
+	invalid.Code = "bc"
+	assert.False(t, validator.IsValid(invalid))
+	invalid = *sample
+	invalid.Address.City = ""
+	assert.False(t, validator.IsValid(&invalid))
+	invalid = *sample
+	invalid.Tags = []string{"x", "toolong"}
+	assert.False(t, validator.IsValid(&invalid))
+	invalid = *sample
+	invalid.Counts = map[string]int{"a": 1, "b": 0}
+	assert.False(t, validator.IsValid(&invalid))
+	assert.False(t, validator.IsValid(1))
+	assert.EqualError(t, validator.Validate(1), "jsonschema: Validate expects a struct, got int; validate JSON with Compile and Schema.Validate")
+	assert.EqualError(t, validator.Validate((*Sample)(nil)), "jsonschema: Validate expects a struct, got *jsonschema.Sample; validate JSON with Compile and Schema.Validate")
+	assert.NoError(t, validator.Validate(sample))
+}
+
+func TestValidationError_Format(t *testing.T) {
+	type Sample struct {
+		Name string `json:"name" jsonschema:"maxLength:3"`
+		Host string `json:"host" jsonschema:"anyOf((format:ipv4),(maxLength:3))"`
+	}
+
+	err := NewValidator().Validate(Sample{Name: "abcd", Host: "example"})
+	ret, ok := err.(*ValidationError)
+	assert.True(t, ok)
+	assert.Equal(t, "String is too long (4 chars), maximum 3; Does not match any schema of anyOf; "+
+		"Format validation failed (); String is too long (7 chars), maximum 3", err.Error())
+
+	flag, _ := json.Marshal(ret.Format(OutputFlag))
+	assert.JSONEq(t, `{"valid": false}`, string(flag))
+
+	valid, _ := json.Marshal((*ValidationError)(nil).Format(OutputFlag))
+	assert.JSONEq(t, `{"valid": true}`, string(valid))
+
+	basic, _ := json.Marshal(ret)
+	assert.JSONEq(t, `{
+		"valid": false,
+		"errors": [
+			{"valid": false, "keywordLocation": "/properties/name/maxLength", "instanceLocation": "/name", "error": "String is too long (4 chars), maximum 3"},
+			{"valid": false, "keywordLocation": "/properties/host/anyOf", "instanceLocation": "/host", "error": "Does not match any schema of anyOf"},
+			{"valid": false, "keywordLocation": "/properties/host/anyOf/0/format", "instanceLocation": "/host", "error": "Format validation failed ()"},
+			{"valid": false, "keywordLocation": "/properties/host/anyOf/1/maxLength", "instanceLocation": "/host", "error": "String is too long (7 chars), maximum 3"}
+		]
+	}`, string(basic))
+
+	detailed, _ := json.Marshal(ret.Format(OutputDetailed))
+	assert.JSONEq(t, `{
+		"valid": false,
+		"keywordLocation": "",
+		"instanceLocation": "",
+		"errors": [
+			{"valid": false, "keywordLocation": "/properties/name/maxLength", "instanceLocation": "/name", "error": "String is too long (4 chars), maximum 3"},
+			{"valid": false, "keywordLocation": "/properties/host/anyOf", "instanceLocation": "/host", "error": "Does not match any schema of anyOf", "errors": [
+				{"valid": false, "keywordLocation": "/properties/host/anyOf/0/format", "instanceLocation": "/host", "error": "Format validation failed ()"},
+				{"valid": false, "keywordLocation": "/properties/host/anyOf/1/maxLength", "instanceLocation": "/host", "error": "String is too long (7 chars), maximum 3"}
+			]}
+		]
+	}`, string(detailed))
+
+	verbose := ret.Format(OutputVerbose)
+	assert.Len(t, verbose.Errors, 2)
+	assert.Equal(t, "/properties/host/anyOf", verbose.Errors[1].Errors[0].KeywordLocation)
+}